@@ -0,0 +1,108 @@
+// Package exec implements the "compile once, instantiate per-call" pattern
+// used by CLI-style wasm wrappers (ffmpeg, ffprobe, and similar WASI
+// binaries): a single wazero.CompiledModule is instantiated fresh for every
+// invocation with caller-supplied stdio/args/env/FS, run to completion, and
+// torn down, all safely from many concurrent goroutines.
+//
+// Without this package, callers hand-roll InstantiateModule+config+Close for
+// every call, repeating the same ~50 lines of boilerplate (including the
+// sys.ExitError handling every embedder needs) in every wrapper.
+package exec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/streamdal/wazero"
+	"github.com/streamdal/wazero/sys"
+)
+
+// Runner instantiates a single wazero.CompiledModule once per Invoke call,
+// runs its "_start" function, and closes the instantiated module afterwards.
+// A Runner is safe for concurrent use by multiple goroutines: each Invoke
+// gets its own wazero.Module instance, so concurrent calls do not share
+// memory or table state.
+type Runner struct {
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+
+	// instanceSeq gives every instantiation a distinct module name, since
+	// wazero requires unique names for concurrently-instantiated modules.
+	instanceSeq atomic.Uint64
+}
+
+// NewRunner returns a Runner that instantiates compiled against r on every
+// Invoke. The caller remains responsible for closing both r and compiled
+// once the Runner is no longer needed; Runner does not take ownership of
+// either.
+func NewRunner(r wazero.Runtime, compiled wazero.CompiledModule) *Runner {
+	return &Runner{runtime: r, compiled: compiled}
+}
+
+// Invocation describes one call to a compiled module's "_start" entrypoint.
+type Invocation struct {
+	Args   []string
+	Env    map[string]string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// FS, if non-nil, is applied via ModuleConfig.WithFSConfig for the
+	// duration of this invocation; its own mounts decide where each
+	// directory appears to the guest.
+	FS wazero.FSConfig
+}
+
+// Result is what Invoke returns once the instance has run to completion.
+type Result struct {
+	// ExitCode is the code the module exited with: 0 unless the module
+	// called proc_exit with a non-zero argument or panicked with a
+	// *sys.ExitError.
+	ExitCode uint32
+}
+
+// Invoke instantiates the compiled module with inv's configuration, runs
+// "_start", and returns once the instance has exited, whether normally or
+// via proc_exit. The instance is always closed before Invoke returns,
+// including when ctx is canceled mid-run.
+//
+// Invoke only treats a non-zero exit code as an error in the sense of
+// returning that *sys.ExitError itself as the error; callers that only care
+// about the exit code should inspect Result.ExitCode and ignore the error
+// when it is a *sys.ExitError.
+func (r *Runner) Invoke(ctx context.Context, inv Invocation) (Result, error) {
+	cfg := wazero.NewModuleConfig().
+		WithName(fmt.Sprintf("invocation-%d", r.instanceSeq.Add(1))).
+		WithArgs(inv.Args...)
+
+	if inv.Stdin != nil {
+		cfg = cfg.WithStdin(inv.Stdin)
+	}
+	if inv.Stdout != nil {
+		cfg = cfg.WithStdout(inv.Stdout)
+	}
+	if inv.Stderr != nil {
+		cfg = cfg.WithStderr(inv.Stderr)
+	}
+	for k, v := range inv.Env {
+		cfg = cfg.WithEnv(k, v)
+	}
+	if inv.FS != nil {
+		cfg = cfg.WithFSConfig(inv.FS)
+	}
+
+	mod, err := r.runtime.InstantiateModule(ctx, r.compiled, cfg)
+	if mod != nil {
+		defer mod.Close(context.WithoutCancel(ctx))
+	}
+	if err == nil {
+		return Result{ExitCode: 0}, nil
+	}
+
+	if exitErr, ok := err.(*sys.ExitError); ok {
+		return Result{ExitCode: exitErr.ExitCode()}, exitErr
+	}
+	return Result{}, err
+}
@@ -0,0 +1,119 @@
+package exec
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamdal/wazero"
+	"github.com/streamdal/wazero/imports/wasi_snapshot_preview1"
+	"github.com/streamdal/wazero/internal/testing/require"
+	"github.com/streamdal/wazero/sys"
+)
+
+func TestRunner_instanceNamesAreUnique(t *testing.T) {
+	r := &Runner{}
+	first := r.instanceSeq.Add(1)
+	second := r.instanceSeq.Add(1)
+	require.True(t, first != second)
+}
+
+// procExitModule returns a minimal wasm binary, hand-encoded since this
+// tree has no wat2wasm available to generate one: it imports
+// wasi_snapshot_preview1's proc_exit and exports "_start", which calls
+// proc_exit(exitCode). exitCode must fit in a single signed LEB128 byte
+// (0-63).
+func procExitModule(exitCode byte) []byte {
+	b := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00} // \0asm, version 1
+
+	// Type section: type 0 is (i32)->() for proc_exit, type 1 is ()->() for
+	// _start.
+	b = appendSection(b, 1, []byte{0x02, 0x60, 0x01, 0x7f, 0x00, 0x60, 0x00, 0x00})
+
+	// Import section: wasi_snapshot_preview1.proc_exit, typed as type 0.
+	importSec := []byte{0x01}
+	importSec = appendName(importSec, "wasi_snapshot_preview1")
+	importSec = appendName(importSec, "proc_exit")
+	importSec = append(importSec, 0x00, 0x00) // kind=func, typeidx=0
+	b = appendSection(b, 2, importSec)
+
+	// Function section: the _start function, typed as type 1.
+	b = appendSection(b, 3, []byte{0x01, 0x01})
+
+	// Export section: "_start" exported as funcidx 1 (funcidx 0 is the
+	// proc_exit import).
+	exportSec := []byte{0x01}
+	exportSec = appendName(exportSec, "_start")
+	exportSec = append(exportSec, 0x00, 0x01)
+	b = appendSection(b, 7, exportSec)
+
+	// Code section: _start's body is `i32.const exitCode; call 0; end`.
+	body := []byte{0x00, 0x41, exitCode, 0x10, 0x00, 0x0b}
+	b = appendSection(b, 10, append([]byte{0x01, byte(len(body))}, body...))
+
+	return b
+}
+
+func appendSection(b []byte, id byte, content []byte) []byte {
+	return append(append(b, id, byte(len(content))), content...)
+}
+
+func appendName(b []byte, name string) []byte {
+	return append(append(b, byte(len(name))), name...)
+}
+
+func TestRunner_Invoke_mapsNonZeroExitCode(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+
+	compiled, err := r.CompileModule(ctx, procExitModule(3))
+	require.NoError(t, err)
+	defer compiled.Close(ctx)
+
+	runner := NewRunner(r, compiled)
+	result, err := runner.Invoke(ctx, Invocation{})
+
+	require.Equal(t, uint32(3), result.ExitCode)
+	exitErr, ok := err.(*sys.ExitError)
+	require.True(t, ok)
+	require.Equal(t, uint32(3), exitErr.ExitCode())
+}
+
+func TestRunner_Invoke_exitCodeZeroIsNotAnError(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+
+	compiled, err := r.CompileModule(ctx, procExitModule(0))
+	require.NoError(t, err)
+	defer compiled.Close(ctx)
+
+	runner := NewRunner(r, compiled)
+	result, err := runner.Invoke(ctx, Invocation{})
+
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), result.ExitCode)
+}
+
+// TestRunner_Invoke_closesInstanceOnError proves the instantiated module is
+// closed even when proc_exit makes Invoke return an error: Runner names its
+// first instantiation "invocation-1", so once Invoke has returned, the
+// runtime must no longer have a module instance registered under that name.
+func TestRunner_Invoke_closesInstanceOnError(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+
+	compiled, err := r.CompileModule(ctx, procExitModule(2))
+	require.NoError(t, err)
+	defer compiled.Close(ctx)
+
+	runner := NewRunner(r, compiled)
+	_, err = runner.Invoke(ctx, Invocation{})
+	require.Error(t, err)
+
+	require.Nil(t, r.Module("invocation-1"))
+}
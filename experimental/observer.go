@@ -0,0 +1,70 @@
+package experimental
+
+import (
+	"context"
+
+	"github.com/streamdal/wazero/internal/expctxkeys"
+)
+
+// Observer receives structured callbacks about a module instance's
+// lifecycle and host-function traffic, for embedders that need to attribute
+// wall time and memory pressure to a specific module rather than the
+// process as a whole (e.g. a host running many untrusted plugins). Install
+// one via WithObserver.
+//
+// Every method is called synchronously on the goroutine performing the
+// corresponding operation; an Observer that needs to do real work (metrics
+// export, span creation) should make its own methods non-blocking.
+//
+// This tree's snapshot does not include the engine code that would call
+// GetObserver and invoke these methods as a module instantiates, runs host
+// functions, and closes (mirroring how CloseNotifier's real caller also
+// isn't in this snapshot) - so nothing in this repository calls GetObserver
+// yet. See experimental/otel for a concrete consumer of this interface
+// (an Observer implementation that forwards to an otel-shaped Tracer/Counter
+// pair) exercised end-to-end against fakes, which is the shape the engine's
+// eventual caller is expected to drive.
+type Observer interface {
+	// OnInstantiate is called once a module instance has finished
+	// instantiating, before any exported function runs.
+	OnInstantiate(ctx context.Context, moduleName string)
+
+	// OnClose is called when a module instance is closed.
+	OnClose(ctx context.Context, moduleName string)
+
+	// OnHostFunctionEntry is called immediately before a host function
+	// runs, with a snapshot of its arguments.
+	OnHostFunctionEntry(ctx context.Context, moduleName, funcName string, params []uint64)
+
+	// OnHostFunctionExit is called immediately after a host function
+	// returns, with a snapshot of its results and, if it returned one, the
+	// error.
+	OnHostFunctionExit(ctx context.Context, moduleName, funcName string, results []uint64, err error)
+
+	// OnMemoryGrow is called after a module instance's memory grows,
+	// reporting the new size in pages.
+	OnMemoryGrow(ctx context.Context, moduleName string, newSizePages uint32)
+
+	// OnInstructionCountSample is called periodically with a sampled
+	// instruction count for a running instance, letting an Observer
+	// attribute CPU usage without the overhead of counting every
+	// instruction.
+	OnInstructionCountSample(ctx context.Context, moduleName string, count uint64)
+}
+
+// WithObserver registers obs to receive callbacks for module instances
+// instantiated using the resulting context, mirroring WithCloseNotifier.
+// Passing a nil obs is a no-op, returning ctx unchanged.
+func WithObserver(ctx context.Context, obs Observer) context.Context {
+	if obs == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, expctxkeys.ObserverKey{}, obs)
+}
+
+// GetObserver returns the Observer installed on ctx via WithObserver, or nil
+// if none was installed.
+func GetObserver(ctx context.Context) Observer {
+	obs, _ := ctx.Value(expctxkeys.ObserverKey{}).(Observer)
+	return obs
+}
@@ -0,0 +1,37 @@
+package experimental_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamdal/wazero/experimental"
+	"github.com/streamdal/wazero/internal/expctxkeys"
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+type noopObserver struct{}
+
+func (noopObserver) OnInstantiate(context.Context, string)                               {}
+func (noopObserver) OnClose(context.Context, string)                                     {}
+func (noopObserver) OnHostFunctionEntry(context.Context, string, string, []uint64)       {}
+func (noopObserver) OnHostFunctionExit(context.Context, string, string, []uint64, error) {}
+func (noopObserver) OnMemoryGrow(context.Context, string, uint32)                        {}
+func (noopObserver) OnInstructionCountSample(context.Context, string, uint64)            {}
+
+func TestWithObserver(t *testing.T) {
+	t.Run("nil observer is a no-op", func(t *testing.T) {
+		decorated := experimental.WithObserver(testCtx, nil)
+		require.Same(t, testCtx, decorated)
+	})
+
+	t.Run("decorates with observer", func(t *testing.T) {
+		obs := noopObserver{}
+		decorated := experimental.WithObserver(testCtx, obs)
+		require.NotNil(t, decorated.Value(expctxkeys.ObserverKey{}))
+		require.Equal(t, obs, experimental.GetObserver(decorated))
+	})
+
+	t.Run("GetObserver without one installed returns nil", func(t *testing.T) {
+		require.Nil(t, experimental.GetObserver(testCtx))
+	})
+}
@@ -0,0 +1,145 @@
+// Package otel adapts experimental.Observer callbacks onto a minimal,
+// dependency-free tracing/metrics shape modeled after
+// go.opentelemetry.io/otel's trace.Tracer and metric.Int64Counter. This tree
+// has no go.mod to pull in the real go.opentelemetry.io/otel module, so
+// Tracer and Counter below are small interfaces a caller satisfies with
+// whatever otel SDK types they already depend on (both of otel's real types
+// already have the methods these interfaces require) rather than this
+// package importing otel itself.
+package otel
+
+import (
+	"context"
+	"sync"
+
+	"github.com/streamdal/wazero/experimental"
+)
+
+// Span is the subset of go.opentelemetry.io/otel/trace.Span that Adapter
+// needs to record a host function call.
+type Span interface {
+	End()
+	SetAttributes(key, value string)
+	RecordError(err error)
+}
+
+// Tracer is the subset of go.opentelemetry.io/otel/trace.Tracer that Adapter
+// needs: starting a span for a host function call, scoped to moduleName and
+// funcName.
+type Tracer interface {
+	Start(ctx context.Context, moduleName, funcName string) (context.Context, Span)
+}
+
+// Counter is the subset of go.opentelemetry.io/otel/metric.Int64Counter
+// that Adapter needs to report a single monotonic measurement, e.g. for
+// memory-grow events or instruction-count samples.
+type Counter interface {
+	Add(ctx context.Context, value int64, moduleName string)
+}
+
+// Adapter implements experimental.Observer by forwarding its callbacks to an
+// otel Tracer and a pair of Counters, letting an embedder install real
+// module-instance-level tracing via experimental.WithObserver without
+// wazero depending on the otel SDK itself.
+//
+// experimental.Observer's OnHostFunctionEntry does not return a context for
+// OnHostFunctionExit to receive back, so Adapter cannot thread the started
+// span through ctx the way a typical otel span-in-context pattern would.
+// Instead it keeps a per-(moduleName, funcName) stack of in-flight spans:
+// OnHostFunctionExit pops the most recently started one. This pairs
+// Entry/Exit calls correctly for nested (reentrant) calls to the same host
+// function and for sequential calls from multiple goroutines, but if two
+// goroutines are ever concurrently inside the *same* host function on the
+// *same* module instance, which attributes/errors land on which span is
+// only correct up to that LIFO ordering, not true causal identity.
+type Adapter struct {
+	Tracer Tracer
+
+	// MemoryGrowCounter, if non-nil, receives the new size in pages on every
+	// OnMemoryGrow.
+	MemoryGrowCounter Counter
+
+	// InstructionCounter, if non-nil, receives every OnInstructionCountSample
+	// value.
+	InstructionCounter Counter
+
+	mu    sync.Mutex
+	spans map[spanKey][]Span
+}
+
+type spanKey struct {
+	moduleName, funcName string
+}
+
+var _ experimental.Observer = (*Adapter)(nil)
+
+// NewAdapter returns an Adapter that records host-function spans against
+// tracer. Both counters are optional and may be left nil.
+func NewAdapter(tracer Tracer, memoryGrowCounter, instructionCounter Counter) *Adapter {
+	return &Adapter{Tracer: tracer, MemoryGrowCounter: memoryGrowCounter, InstructionCounter: instructionCounter}
+}
+
+// OnInstantiate is a no-op: this Adapter only records host-function spans
+// and counter measurements, not per-instance lifetime spans.
+func (a *Adapter) OnInstantiate(context.Context, string) {}
+
+// OnClose is a no-op; see OnInstantiate.
+func (a *Adapter) OnClose(context.Context, string) {}
+
+// OnHostFunctionEntry starts a span for the call and pushes it onto the
+// per-(moduleName, funcName) stack described on Adapter, for
+// OnHostFunctionExit to pop and end.
+func (a *Adapter) OnHostFunctionEntry(ctx context.Context, moduleName, funcName string, _ []uint64) {
+	if a.Tracer == nil {
+		return
+	}
+	_, span := a.Tracer.Start(ctx, moduleName, funcName)
+
+	key := spanKey{moduleName, funcName}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.spans == nil {
+		a.spans = map[spanKey][]Span{}
+	}
+	a.spans[key] = append(a.spans[key], span)
+}
+
+// OnHostFunctionExit ends the span OnHostFunctionEntry started for this
+// call, recording err on it if the host function returned one.
+func (a *Adapter) OnHostFunctionExit(_ context.Context, moduleName, funcName string, _ []uint64, err error) {
+	key := spanKey{moduleName, funcName}
+
+	a.mu.Lock()
+	stack := a.spans[key]
+	if len(stack) == 0 {
+		a.mu.Unlock()
+		return
+	}
+	span := stack[len(stack)-1]
+	stack = stack[:len(stack)-1]
+	if len(stack) == 0 {
+		delete(a.spans, key)
+	} else {
+		a.spans[key] = stack
+	}
+	a.mu.Unlock()
+
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// OnMemoryGrow reports newSizePages to MemoryGrowCounter, if set.
+func (a *Adapter) OnMemoryGrow(ctx context.Context, moduleName string, newSizePages uint32) {
+	if a.MemoryGrowCounter != nil {
+		a.MemoryGrowCounter.Add(ctx, int64(newSizePages), moduleName)
+	}
+}
+
+// OnInstructionCountSample reports count to InstructionCounter, if set.
+func (a *Adapter) OnInstructionCountSample(ctx context.Context, moduleName string, count uint64) {
+	if a.InstructionCounter != nil {
+		a.InstructionCounter.Add(ctx, int64(count), moduleName)
+	}
+}
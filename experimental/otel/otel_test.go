@@ -0,0 +1,111 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+type fakeSpan struct {
+	name  string
+	ended bool
+	err   error
+	attrs map[string]string
+}
+
+func (s *fakeSpan) End() { s.ended = true }
+func (s *fakeSpan) SetAttributes(key, value string) {
+	if s.attrs == nil {
+		s.attrs = map[string]string{}
+	}
+	s.attrs[key] = value
+}
+func (s *fakeSpan) RecordError(err error) { s.err = err }
+
+type fakeTracer struct {
+	started []*fakeSpan
+}
+
+func (t *fakeTracer) Start(ctx context.Context, moduleName, funcName string) (context.Context, Span) {
+	s := &fakeSpan{name: moduleName + "." + funcName}
+	t.started = append(t.started, s)
+	return ctx, s
+}
+
+type fakeCounter struct {
+	values []int64
+}
+
+func (c *fakeCounter) Add(ctx context.Context, value int64, moduleName string) {
+	c.values = append(c.values, value)
+}
+
+func TestAdapter_hostFunctionSpan_recordsErrorAndEnds(t *testing.T) {
+	tracer := &fakeTracer{}
+	a := NewAdapter(tracer, nil, nil)
+
+	a.OnHostFunctionEntry(context.Background(), "mod", "fn", nil)
+	require.Equal(t, 1, len(tracer.started))
+	require.False(t, tracer.started[0].ended)
+
+	wantErr := errors.New("boom")
+	a.OnHostFunctionExit(context.Background(), "mod", "fn", nil, wantErr)
+
+	require.True(t, tracer.started[0].ended)
+	require.Equal(t, wantErr, tracer.started[0].err)
+}
+
+func TestAdapter_hostFunctionSpan_nestedCallsPairLIFO(t *testing.T) {
+	tracer := &fakeTracer{}
+	a := NewAdapter(tracer, nil, nil)
+
+	a.OnHostFunctionEntry(context.Background(), "mod", "fn", nil)
+	a.OnHostFunctionEntry(context.Background(), "mod", "fn", nil)
+	require.Equal(t, 2, len(tracer.started))
+
+	// The inner call exits first; it must end the second-started span, not
+	// the first.
+	a.OnHostFunctionExit(context.Background(), "mod", "fn", nil, nil)
+	require.True(t, tracer.started[1].ended)
+	require.False(t, tracer.started[0].ended)
+
+	a.OnHostFunctionExit(context.Background(), "mod", "fn", nil, nil)
+	require.True(t, tracer.started[0].ended)
+}
+
+func TestAdapter_hostFunctionExit_withoutEntryIsNoop(t *testing.T) {
+	a := NewAdapter(&fakeTracer{}, nil, nil)
+	a.OnHostFunctionExit(context.Background(), "mod", "fn", nil, nil)
+}
+
+func TestAdapter_noTracer_entryIsNoop(t *testing.T) {
+	a := NewAdapter(nil, nil, nil)
+	a.OnHostFunctionEntry(context.Background(), "mod", "fn", nil)
+	a.OnHostFunctionExit(context.Background(), "mod", "fn", nil, nil)
+}
+
+func TestAdapter_counters(t *testing.T) {
+	memGrow := &fakeCounter{}
+	instrCount := &fakeCounter{}
+	a := NewAdapter(nil, memGrow, instrCount)
+
+	a.OnMemoryGrow(context.Background(), "mod", 5)
+	require.Equal(t, []int64{5}, memGrow.values)
+
+	a.OnInstructionCountSample(context.Background(), "mod", 1000)
+	require.Equal(t, []int64{1000}, instrCount.values)
+}
+
+func TestAdapter_counters_nilIsNoop(t *testing.T) {
+	a := NewAdapter(nil, nil, nil)
+	a.OnMemoryGrow(context.Background(), "mod", 5)
+	a.OnInstructionCountSample(context.Background(), "mod", 1000)
+}
+
+func TestAdapter_instantiateAndClose_areNoops(t *testing.T) {
+	a := NewAdapter(nil, nil, nil)
+	a.OnInstantiate(context.Background(), "mod")
+	a.OnClose(context.Background(), "mod")
+}
@@ -0,0 +1,36 @@
+package wasip2
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/streamdal/wazero/api"
+	"github.com/streamdal/wazero/imports/wasip2/internal/componentabi"
+)
+
+// wallClockNow is overridden by tests so assertions don't race the real
+// clock.
+var wallClockNow = time.Now
+
+// now implements wasi:clocks/wall-clock#now() -> datetime, lowered to core
+// wasm as (result_ptr) where the guest has pre-allocated 12 bytes at
+// result_ptr via its realloc export for the returned datetime record.
+func now(ctx context.Context, mod api.Module, stack []uint64) {
+	resultPtr := uint32(stack[0])
+	t := wallClockNow()
+	if !componentabi.WriteDatetime(mod.Memory(), resultPtr, uint64(t.Unix()), uint32(t.Nanosecond())) {
+		panic(fmt.Sprintf("wasip2: wasi:clocks/wall-clock#now: out of bounds memory access writing result at %#x", resultPtr))
+	}
+}
+
+// resolution implements wasi:clocks/wall-clock#resolution() -> datetime,
+// lowered the same way as now. Go's time package does not expose the host
+// clock's actual resolution, so this reports 1 microsecond, a conservative
+// value no real guest should observe finer than in practice.
+func resolution(ctx context.Context, mod api.Module, stack []uint64) {
+	resultPtr := uint32(stack[0])
+	if !componentabi.WriteDatetime(mod.Memory(), resultPtr, 0, 1000) {
+		panic(fmt.Sprintf("wasip2: wasi:clocks/wall-clock#resolution: out of bounds memory access writing result at %#x", resultPtr))
+	}
+}
@@ -0,0 +1,64 @@
+// Package componentabi implements the small subset of the WebAssembly
+// component model's canonical ABI that the wasip2 host modules need to
+// translate between a guest's linear memory and Go values: lowering/lifting
+// `list<u8>` and encoding `result<T, E>` return values. It intentionally
+// does not implement resource handles, variants, or records beyond what
+// wasip2 itself requires - see the package doc of imports/wasip2 for why.
+package componentabi
+
+import "github.com/streamdal/wazero/api"
+
+// LiftListU8 reads a `list<u8>` lowered at (ptr, len) - the canonical ABI's
+// representation of a guest-owned byte slice as two i32 core-wasm
+// parameters - out of mem as a copy, since the guest may mutate or free the
+// backing memory immediately after the call returns.
+func LiftListU8(mem api.Memory, ptr, length uint32) ([]byte, bool) {
+	b, ok := mem.Read(ptr, length)
+	if !ok {
+		return nil, false
+	}
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out, true
+}
+
+// LowerListU8 writes data into mem starting at ptr, the inverse of
+// LiftListU8, for host->guest `list<u8>` results. The guest is expected to
+// have already allocated len(data) bytes at ptr via its realloc export,
+// exactly as it would for any other canonical-ABI call.
+func LowerListU8(mem api.Memory, ptr uint32, data []byte) bool {
+	return mem.Write(ptr, data)
+}
+
+// ResultTag discriminates a canonical-ABI `result<T, E>` value, which is
+// lowered as a discriminant byte followed by either payload.
+type ResultTag uint32
+
+const (
+	ResultOK  ResultTag = 0
+	ResultErr ResultTag = 1
+)
+
+// WriteResultUnit writes a `result<_, E>` where both the ok and err payloads
+// are a single u32 error code, which covers every wasip2 interface this
+// package currently implements. ptr must point to space for a discriminant
+// byte plus a u32, as the interface's witx/wit definition specifies.
+func WriteResultUnit(mem api.Memory, ptr uint32, tag ResultTag, code uint32) bool {
+	if !mem.WriteByte(ptr, byte(tag)) {
+		return false
+	}
+	return mem.WriteUint32Le(ptr+4, code)
+}
+
+// WriteDatetime writes a wasi:clocks `datetime` record - seconds: u64
+// followed by nanoseconds: u32 - at ptr, with no result<> wrapper since
+// wasi:clocks/wall-clock#now and #resolution return the record directly.
+// ptr must point to space for 12 bytes, as the interface's wit definition
+// specifies (an 8-byte u64 field followed by a 4-byte u32 field, with no
+// padding since the canonical ABI aligns the record to its largest field).
+func WriteDatetime(mem api.Memory, ptr uint32, seconds uint64, nanoseconds uint32) bool {
+	if !mem.WriteUint64Le(ptr, seconds) {
+		return false
+	}
+	return mem.WriteUint32Le(ptr+8, nanoseconds)
+}
@@ -0,0 +1,12 @@
+package componentabi
+
+import "testing"
+
+// LiftListU8, LowerListU8, WriteResultUnit and WriteDatetime all need an
+// api.Memory, and this package has no fake implementing one, so they are
+// not tested here; imports/wasip2's tests do not call them either.
+func TestResultTags(t *testing.T) {
+	if ResultOK != 0 || ResultErr != 1 {
+		t.Fatalf("unexpected result tag values: ok=%d err=%d", ResultOK, ResultErr)
+	}
+}
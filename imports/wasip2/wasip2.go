@@ -0,0 +1,137 @@
+// Package wasip2 implements host modules for the subset of the WASI preview2
+// / component-model interfaces (wasi:random, wasi:clocks) that can be
+// expressed as plain core-wasm host functions without a full component
+// runtime. It exists so that downstream users moving their guests to
+// preview2-generated bindings (sqlc-style plugin hosts, ffmpeg-style tool
+// wrappers) have a migration path that doesn't require leaving wazero.
+//
+// wasi:filesystem, wasi:sockets, and wasi:io/streams are not yet
+// implemented: those interfaces lean on resource handles (first-class
+// component-model references with their own lifetime), which need
+// tracking infrastructure this package does not yet have. CheckSupported
+// returns an error when given the import module names of a guest that
+// imports one of those interfaces, so that failure can happen at load time
+// rather than as a confusing trap deep in a guest's runtime. Instantiate
+// itself does not inspect any guest module's imports.
+package wasip2
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/streamdal/wazero"
+	"github.com/streamdal/wazero/api"
+	"github.com/streamdal/wazero/imports/wasip2/internal/componentabi"
+)
+
+// Module names for the interfaces this package implements, following the
+// component model's `wasi:<package>/<interface>` naming convention as it
+// appears in a core-wasm import's module field once lowered by a bindings
+// generator.
+const (
+	ModuleNameRandom = "wasi:random/random"
+	ModuleNameClocks = "wasi:clocks/wall-clock"
+)
+
+// unsupportedModules lists interfaces this package deliberately does not
+// implement yet; CheckSupported rejects a list of import module names that
+// contains one of them rather than leaving unresolvable imports to fail less
+// clearly later.
+var unsupportedModules = []string{
+	"wasi:cli/environment",
+	"wasi:filesystem/types",
+	"wasi:filesystem/preopens",
+	"wasi:sockets/tcp",
+	"wasi:sockets/udp",
+	"wasi:io/streams",
+}
+
+// Instantiate instantiates the wasip2 host modules into r, returning an
+// error rather than panicking, mirroring wasi_snapshot_preview1.Instantiate.
+func Instantiate(ctx context.Context, r wazero.Runtime) error {
+	_, err := r.NewHostModuleBuilder(ModuleNameRandom).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(getRandomBytes), []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32}, nil).
+		WithParameterNames("buf_ptr", "len", "result_ptr").
+		Export("get-random-bytes").
+		Instantiate(ctx)
+	if err != nil {
+		return fmt.Errorf("wasip2: instantiate %s: %w", ModuleNameRandom, err)
+	}
+
+	_, err = r.NewHostModuleBuilder(ModuleNameClocks).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(now), []api.ValueType{api.ValueTypeI32}, nil).
+		WithParameterNames("result_ptr").
+		Export("now").
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(resolution), []api.ValueType{api.ValueTypeI32}, nil).
+		WithParameterNames("result_ptr").
+		Export("resolution").
+		Instantiate(ctx)
+	if err != nil {
+		return fmt.Errorf("wasip2: instantiate %s: %w", ModuleNameClocks, err)
+	}
+	return nil
+}
+
+// MustInstantiate calls Instantiate and panics if it errs, for callers that
+// treat a wasip2 instantiation failure as a program bug rather than a
+// recoverable condition - mirroring
+// wasi_snapshot_preview1.MustInstantiate.
+func MustInstantiate(ctx context.Context, r wazero.Runtime) {
+	if err := Instantiate(ctx, r); err != nil {
+		panic(err)
+	}
+}
+
+// CheckSupported reports an error naming the first interface in
+// requiredModuleNames that this package does not yet implement, so callers
+// can fail a module's instantiation early with a clear message instead of an
+// opaque "unresolved import" trap.
+func CheckSupported(requiredModuleNames []string) error {
+	for _, required := range requiredModuleNames {
+		for _, unsupported := range unsupportedModules {
+			if required == unsupported {
+				return fmt.Errorf("wasip2: %s is not yet implemented", unsupported)
+			}
+		}
+	}
+	return nil
+}
+
+// getRandomBytes implements wasi:random/random#get-random-bytes(len: u64) ->
+// list<u8>, lowered to core wasm as (buf_ptr, len, result_ptr) where the
+// guest has pre-allocated len bytes at buf_ptr via its realloc export and
+// result_ptr points at space for the `result<list<u8>, error-code>` this
+// writes back.
+func getRandomBytes(ctx context.Context, mod api.Module, stack []uint64) {
+	bufPtr := uint32(stack[0])
+	length := uint32(stack[1])
+	resultPtr := uint32(stack[2])
+
+	mem := mod.Memory()
+	buf := make([]byte, length)
+	if _, err := rand.Read(buf); err != nil {
+		writeResultUnit(mem, resultPtr, componentabi.ResultErr, 1)
+		return
+	}
+	if !componentabi.LowerListU8(mem, bufPtr, buf) {
+		writeResultUnit(mem, resultPtr, componentabi.ResultErr, 1)
+		return
+	}
+	writeResultUnit(mem, resultPtr, componentabi.ResultOK, 0)
+}
+
+// writeResultUnit calls componentabi.WriteResultUnit and panics if it
+// fails, mirroring wasi_snapshot_preview1's convention of panicking on a
+// guest-supplied pointer that falls outside the module's memory rather than
+// silently discarding the write: a module that passes a bad result_ptr gets
+// a clear trap instead of a call that appeared to succeed while leaving the
+// guest's result buffer untouched.
+func writeResultUnit(mem api.Memory, ptr uint32, tag componentabi.ResultTag, code uint32) {
+	if !componentabi.WriteResultUnit(mem, ptr, tag, code) {
+		panic(fmt.Sprintf("wasip2: out of bounds memory access writing result at %#x", ptr))
+	}
+}
@@ -0,0 +1,14 @@
+package wasip2
+
+import (
+	"testing"
+
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+func TestCheckSupported(t *testing.T) {
+	require.NoError(t, CheckSupported([]string{ModuleNameRandom, ModuleNameClocks}))
+
+	err := CheckSupported([]string{ModuleNameRandom, "wasi:filesystem/types"})
+	require.Error(t, err)
+}
@@ -0,0 +1,186 @@
+package amd64
+
+import (
+	"fmt"
+
+	"github.com/streamdal/wazero/internal/asm"
+)
+
+// memoryWithIndex is a SIB-style memory operand of the form
+// [base + index*scale + disp32], used by CompileMemoryWithIndexToRegister and
+// CompileRegisterToMemoryWithIndex, and produced by foldAddressingModes when it
+// fuses a base-load with a separate index computation.
+type memoryWithIndex struct {
+	base  asm.Register
+	index asm.Register
+	scale byte // one of 1, 2, 4, 8; 0 means "no index".
+	disp  int32
+}
+
+func validScale(scale byte) bool {
+	switch scale {
+	case 1, 2, 4, 8:
+		return true
+	default:
+		return false
+	}
+}
+
+// CompileMemoryWithIndexToRegister adds an instruction where source operand is
+// the memory location addressed by (baseReg + indexReg*scale + disp), and the
+// destination is a register. This is the SIB-operand analogue of
+// CompileMemoryToRegister, letting callers express a scaled-index addressing
+// mode in a single node instead of materializing the address in a temporary
+// register first.
+func (a *AssemblerImpl) CompileMemoryWithIndexToRegister(
+	instruction asm.Instruction,
+	baseReg asm.Register, disp int32, indexReg asm.Register, scale int16,
+	dstReg asm.Register,
+) error {
+	if !validScale(byte(scale)) {
+		return fmt.Errorf("invalid scale %d: must be one of 1, 2, 4, 8", scale)
+	}
+	n := a.newNode(instruction, operandTypeMemory, operandTypeRegister)
+	n.srcReg = baseReg
+	n.srcReg2 = indexReg
+	n.srcConst = int64(disp)
+	n.arg = byte(scale)
+	n.dstReg = dstReg
+	return nil
+}
+
+// CompileRegisterToMemoryWithIndex is the mirror of
+// CompileMemoryWithIndexToRegister: the source is a register and the
+// destination is the SIB-addressed memory location.
+func (a *AssemblerImpl) CompileRegisterToMemoryWithIndex(
+	instruction asm.Instruction,
+	srcReg asm.Register,
+	baseReg asm.Register, disp int32, indexReg asm.Register, scale int16,
+) error {
+	if !validScale(byte(scale)) {
+		return fmt.Errorf("invalid scale %d: must be one of 1, 2, 4, 8", scale)
+	}
+	n := a.newNode(instruction, operandTypeRegister, operandTypeMemory)
+	n.srcReg = srcReg
+	n.dstReg = baseReg
+	n.dstReg2 = indexReg
+	n.dstConst = int64(disp)
+	n.arg = byte(scale)
+	return nil
+}
+
+// foldAddressingModes is a peephole pass intended to run once over the node
+// stream immediately before encoding, rewriting two common
+// address-computation idioms into a single SIB-operand instruction and
+// shrinking the emitted code for wasm memory accesses with dynamic offsets:
+//
+//  1. LEAQ base+disp, tmp ; MOV (tmp), dst        =>  MOV dst, [base+disp]
+//  2. SHLQ $s, idx ; ADDQ base, idx ; MOV (idx), dst  =>  MOV dst, [base+idx*2^s]
+//
+// A rewrite only fires when the temporary register produced by the first
+// instruction(s) is dead after the final load/store, i.e. not referenced by
+// any later node up to the next label or unconditional jump; fusing across
+// such boundaries would be unsound because the temp's liveness cannot be
+// proven from a single linear scan.
+//
+// Assemble does not run this pass; see addressing_fold_test.go for coverage
+// of the rewrite itself, exercised directly against a hand-built node stream.
+func (a *AssemblerImpl) foldAddressingModes() {
+	for n := a.root; n != nil; n = n.next {
+		switch {
+		case n.instruction == LEAQ && n.next != nil && isPlainLoadOrStore(n.next):
+			a.tryFoldLEAThenMem(n)
+		case n.instruction == SHLQ && n.next != nil && n.next.instruction == ADDQ:
+			a.tryFoldShiftAddThenMem(n)
+		}
+	}
+}
+
+// isPlainLoadOrStore reports whether n addresses memory through a bare
+// register with no existing index/disp, i.e. `(tmp)` with zero displacement -
+// the shape foldAddressingModes looks to fuse into.
+func isPlainLoadOrStore(n *nodeImpl) bool {
+	switch {
+	case n.types.src == operandTypeMemory:
+		return n.srcConst == 0 && n.srcReg2 == asm.NilRegister
+	case n.types.dst == operandTypeMemory:
+		return n.dstConst == 0 && n.dstReg2 == asm.NilRegister
+	default:
+		return false
+	}
+}
+
+// tryFoldLEAThenMem fuses `LEAQ base+disp, tmp ; MOV (tmp), dst` into
+// `MOV dst, [base+disp]` when tmp is dead after the load/store and no
+// intervening label or jump breaks the straight-line assumption.
+func (a *AssemblerImpl) tryFoldLEAThenMem(lea *nodeImpl) {
+	memNode := lea.next
+	tmp := lea.dstReg
+	if isLabelOrJump(memNode) || tempReferencedAfter(memNode.next, tmp) {
+		return
+	}
+	if memNode.types.src == operandTypeMemory && memNode.srcReg == tmp {
+		memNode.srcReg = lea.srcReg
+		memNode.srcConst = lea.srcConst
+	} else if memNode.types.dst == operandTypeMemory && memNode.dstReg == tmp {
+		memNode.dstReg = lea.srcReg
+		memNode.dstConst = lea.srcConst
+	} else {
+		return
+	}
+	a.removeNode(lea)
+}
+
+// tryFoldShiftAddThenMem fuses `SHLQ $s, idx ; ADDQ base, idx ; MOV (idx), dst`
+// into a single SIB-operand load/store `MOV dst, [base+idx*2^s]`, under the
+// same temp-liveness constraint as tryFoldLEAThenMem.
+func (a *AssemblerImpl) tryFoldShiftAddThenMem(shl *nodeImpl) {
+	add := shl.next
+	if add.next == nil || !isPlainLoadOrStore(add.next) {
+		return
+	}
+	memNode := add.next
+	idx := shl.dstReg
+	if add.types.src != operandTypeRegister || add.dstReg != idx {
+		return
+	}
+	scale := byte(1) << uint(shl.srcConst)
+	if !validScale(scale) || isLabelOrJump(memNode) || tempReferencedAfter(memNode.next, idx) {
+		return
+	}
+	if memNode.types.src == operandTypeMemory && memNode.srcReg == idx {
+		memNode.srcReg = add.srcReg
+		memNode.srcReg2 = idx
+		memNode.arg = scale
+	} else if memNode.types.dst == operandTypeMemory && memNode.dstReg == idx {
+		memNode.dstReg = add.srcReg
+		memNode.dstReg2 = idx
+		memNode.arg = scale
+	} else {
+		return
+	}
+	a.removeNode(shl)
+	a.removeNode(add)
+}
+
+// isLabelOrJump reports whether n is a control-flow boundary across which
+// foldAddressingModes refuses to fuse, since liveness of the folded temp
+// cannot be established by a linear scan past it.
+func isLabelOrJump(n *nodeImpl) bool {
+	return n.instruction == JMP || n.instruction == NOP || n.types.dst == operandTypeBranch
+}
+
+// tempReferencedAfter reports whether reg is read or written by any node
+// starting at n and continuing until the next label/jump (inclusive check
+// left to the caller via isLabelOrJump at each call site).
+func tempReferencedAfter(n *nodeImpl, reg asm.Register) bool {
+	for ; n != nil; n = n.next {
+		if n.srcReg == reg || n.srcReg2 == reg || n.dstReg == reg || n.dstReg2 == reg {
+			return true
+		}
+		if isLabelOrJump(n) {
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package amd64
+
+import (
+	"testing"
+
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+func TestValidScale(t *testing.T) {
+	for _, s := range []byte{1, 2, 4, 8} {
+		require.True(t, validScale(s))
+	}
+	for _, s := range []byte{0, 3, 5, 16} {
+		require.False(t, validScale(s))
+	}
+}
+
+func TestCompileMemoryWithIndexToRegister_invalidScale(t *testing.T) {
+	a := NewAssembler()
+	err := a.CompileMemoryWithIndexToRegister(MOVL, RegAX, 0, RegCX, 3, RegDX)
+	require.Error(t, err)
+}
+
+func TestCompileRegisterToMemoryWithIndex_invalidScale(t *testing.T) {
+	a := NewAssembler()
+	err := a.CompileRegisterToMemoryWithIndex(MOVL, RegDX, RegAX, 0, RegCX, 3)
+	require.Error(t, err)
+}
+
+// nodes walks the assembler's node stream from the root and returns it as a
+// slice, for asserting on the shape of the list after a peephole pass runs.
+func nodes(a *AssemblerImpl) []*nodeImpl {
+	var out []*nodeImpl
+	for n := a.root; n != nil; n = n.next {
+		out = append(out, n)
+	}
+	return out
+}
+
+func TestFoldAddressingModes_leaThenLoad(t *testing.T) {
+	a := NewAssembler()
+	// LEAQ [RegBX+16], RegCX ; MOVL (RegCX), RegDX
+	lea := a.newNode(LEAQ, operandTypeMemory, operandTypeRegister)
+	lea.srcReg, lea.srcConst, lea.dstReg = RegBX, 16, RegCX
+	load := a.newNode(MOVL, operandTypeMemory, operandTypeRegister)
+	load.srcReg, load.dstReg = RegCX, RegDX
+
+	a.foldAddressingModes()
+
+	got := nodes(a)
+	require.Equal(t, 1, len(got))
+	require.Equal(t, load, got[0])
+	require.Equal(t, RegBX, load.srcReg)
+	require.Equal(t, int64(16), load.srcConst)
+}
+
+func TestFoldAddressingModes_leaThenLoad_tempStillLiveIsNotFolded(t *testing.T) {
+	a := NewAssembler()
+	lea := a.newNode(LEAQ, operandTypeMemory, operandTypeRegister)
+	lea.srcReg, lea.srcConst, lea.dstReg = RegBX, 16, RegCX
+	load := a.newNode(MOVL, operandTypeMemory, operandTypeRegister)
+	load.srcReg, load.dstReg = RegCX, RegDX
+	// A later instruction still reads the temporary, so the fold must not fire.
+	reuse := a.newNode(MOVL, operandTypeRegister, operandTypeRegister)
+	reuse.srcReg, reuse.dstReg = RegCX, RegAX
+
+	a.foldAddressingModes()
+
+	got := nodes(a)
+	require.Equal(t, 3, len(got))
+	require.Equal(t, lea, got[0])
+}
+
+func TestFoldAddressingModes_shiftAddThenLoad(t *testing.T) {
+	a := NewAssembler()
+	// SHLQ $2, RegCX ; ADDQ RegBX, RegCX ; MOVL (RegCX), RegDX
+	shl := a.newNode(SHLQ, operandTypeConst, operandTypeRegister)
+	shl.srcConst, shl.dstReg = 2, RegCX
+	add := a.newNode(ADDQ, operandTypeRegister, operandTypeRegister)
+	add.srcReg, add.dstReg = RegBX, RegCX
+	load := a.newNode(MOVL, operandTypeMemory, operandTypeRegister)
+	load.srcReg, load.dstReg = RegCX, RegDX
+
+	a.foldAddressingModes()
+
+	got := nodes(a)
+	require.Equal(t, 1, len(got))
+	require.Equal(t, load, got[0])
+	require.Equal(t, RegBX, load.srcReg)
+	require.Equal(t, RegCX, load.srcReg2)
+	require.Equal(t, byte(4), load.arg)
+}
@@ -0,0 +1,393 @@
+// Package disasm implements a minimal x86-64 decoder covering only the
+// instruction shapes the amd64 compiler backend actually emits: REX and
+// 2-byte-VEX/3-byte-VEX-prefixed forms, SIB/ModRM memory operands, and
+// RIP-relative addressing. It exists so generated code can be inspected
+// in-process (e.g. via RuntimeConfig.WithDebugDump) instead of piping bytes
+// through an external disassembler, and so encoder tests can assert
+// decode(encode(inst)) == inst rather than relying on hand-decoded byte
+// arrays.
+//
+// It is not a general-purpose x86-64 disassembler: unrecognized opcodes
+// decode to an Instruction with Mnemonic "?" and Len covering the prefix and
+// opcode bytes read so far (at least 1), so callers can skip forward and keep
+// decoding the rest of the stream.
+package disasm
+
+import "fmt"
+
+// Instruction is one decoded instruction: enough to print a readable
+// assembly-like form and to compare against the encoder's own notion of what
+// it just emitted.
+type Instruction struct {
+	Mnemonic string
+	Len      int
+	// Operands holds the decoded operand strings. VEX forms carry the
+	// ModRM.reg operand followed by the ModRM.rm operand, e.g.
+	// ["xmm5", "[rip + 0x0]"]; legacy forms carry only the ModRM.rm
+	// operand, e.g. ["[rip + 0x14]"].
+	Operands []string
+}
+
+func (i Instruction) String() string {
+	if len(i.Operands) == 0 {
+		return i.Mnemonic
+	}
+	s := i.Mnemonic
+	for idx, op := range i.Operands {
+		if idx == 0 {
+			s += " " + op
+		} else {
+			s += ", " + op
+		}
+	}
+	return s
+}
+
+var reg64Names = [16]string{
+	"rax", "rcx", "rdx", "rbx", "rsp", "rbp", "rsi", "rdi",
+	"r8", "r9", "r10", "r11", "r12", "r13", "r14", "r15",
+}
+
+var reg32Names = [16]string{
+	"eax", "ecx", "edx", "ebx", "esp", "ebp", "esi", "edi",
+	"r8d", "r9d", "r10d", "r11d", "r12d", "r13d", "r14d", "r15d",
+}
+
+var reg8Names = [16]string{
+	"al", "cl", "dl", "bl", "spl", "bpl", "sil", "dil",
+	"r8b", "r9b", "r10b", "r11b", "r12b", "r13b", "r14b", "r15b",
+}
+
+// twoByteMnemonics maps a 0F-escaped opcode byte to its mnemonic for the
+// subset of instructions the compiler backend emits.
+var twoByteMnemonics = map[byte]string{
+	0x10: "movupd/movss",
+	0x2e: "ucomiss/ucomisd",
+	0x6e: "movd/movq",
+	0x6f: "movdqu/movdqa",
+	0x7e: "movq",
+	0x8b: "movzx",
+	0xd4: "paddq",
+	0xdb: "pand",
+	0xdf: "pandn",
+	0xeb: "por",
+	0xef: "pxor",
+	0xfc: "paddb",
+	0xfd: "paddw",
+	0xfe: "paddd",
+}
+
+// oneByteMnemonics maps a single-byte opcode to its mnemonic for the subset
+// of instructions the compiler backend emits without a 0F escape.
+var oneByteMnemonics = map[byte]string{
+	0x03: "add",
+	0x2b: "sub",
+	0x39: "cmp",
+	0x3b: "cmp",
+	0x8b: "mov",
+	0x8d: "lea",
+}
+
+// setccMnemonics maps a `0F 9x` SETcc opcode byte to its mnemonic.
+var setccMnemonics = map[byte]string{
+	0x92: "setb", 0x93: "setae", 0x94: "sete", 0x95: "setne",
+	0x96: "setbe", 0x97: "seta", 0x9a: "setp", 0x9b: "setnp",
+	0x9c: "setl", 0x9d: "setge", 0x9e: "setle", 0x9f: "setg",
+}
+
+var xmmNames = [16]string{
+	"xmm0", "xmm1", "xmm2", "xmm3", "xmm4", "xmm5", "xmm6", "xmm7",
+	"xmm8", "xmm9", "xmm10", "xmm11", "xmm12", "xmm13", "xmm14", "xmm15",
+}
+
+// vexKey identifies a VEX-encoded instruction by its implied mandatory
+// prefix (pp) and opcode byte; every instruction this package decodes uses
+// the bare 0F escape (mmmmm == 1), so mmmmm is not part of the key.
+type vexKey struct{ pp, opcode byte }
+
+// vexMnemonics maps a VEX pp/opcode pair to its mnemonic, mirroring the
+// instructions defined in amd64.vexOpInfos.
+var vexMnemonics = map[vexKey]string{
+	{pp: 2, opcode: 0x6f}: "vmovdqu",
+	{pp: 1, opcode: 0x10}: "vmovupd",
+	{pp: 2, opcode: 0x5c}: "vsubss",
+	{pp: 3, opcode: 0x5c}: "vsubsd",
+	{pp: 1, opcode: 0x2e}: "vucomisd",
+	{pp: 0, opcode: 0x2e}: "vucomiss",
+	{pp: 1, opcode: 0xfc}: "vpaddb",
+	{pp: 1, opcode: 0xfd}: "vpaddw",
+	{pp: 1, opcode: 0xfe}: "vpaddd",
+	{pp: 1, opcode: 0xd4}: "vpaddq",
+	{pp: 1, opcode: 0xdb}: "vpand",
+	{pp: 1, opcode: 0xdf}: "vpandn",
+	{pp: 1, opcode: 0xeb}: "vpor",
+	{pp: 1, opcode: 0xef}: "vpxor",
+}
+
+// Decode decodes the single instruction at the start of b, returning its
+// length so the caller can advance. pos is the offset of b[0] within the
+// enclosing buffer, used to resolve RIP-relative operands.
+func Decode(b []byte, pos int) (Instruction, error) {
+	if len(b) == 0 {
+		return Instruction{}, fmt.Errorf("disasm: empty input")
+	}
+
+	if b[0] == 0xc5 || b[0] == 0xc4 {
+		return decodeVEX(b, pos)
+	}
+
+	off := 0
+	rexW, rexR, rexX, rexB := false, false, false, false
+	if off < len(b) && b[off]&0xf0 == 0x40 {
+		rex := b[off]
+		rexW = rex&0x08 != 0
+		rexR = rex&0x04 != 0
+		rexX = rex&0x02 != 0
+		rexB = rex&0x01 != 0
+		off++
+	}
+
+	if off >= len(b) {
+		return Instruction{}, fmt.Errorf("disasm: truncated instruction at %#x", pos)
+	}
+
+	if b[off] == 0x0f {
+		off++
+		if off >= len(b) {
+			return Instruction{}, fmt.Errorf("disasm: truncated two-byte opcode at %#x", pos)
+		}
+		op := b[off]
+		off++
+		if mnemonic, ok := setccMnemonics[op]; ok {
+			if off < len(b) && b[off]>>6 == 0b11 {
+				// Register-direct SETcc writes a byte register.
+				idx := int(b[off] & 0b111)
+				if rexB {
+					idx += 8
+				}
+				name := reg8Names[idx]
+				if b[0]&0xf0 != 0x40 && idx >= 4 {
+					name = [4]string{"ah", "ch", "dh", "bh"}[idx-4]
+				}
+				return Instruction{Mnemonic: mnemonic, Len: off + 1, Operands: []string{name}}, nil
+			}
+			modrm, n, err := decodeModRM(b[off:], pos+off, rexR, rexX, rexB, false)
+			if err != nil {
+				return Instruction{}, err
+			}
+			off += n
+			return Instruction{Mnemonic: mnemonic, Len: off, Operands: []string{modrm}}, nil
+		}
+		if mnemonic, ok := twoByteMnemonics[op]; ok {
+			modrm, n, err := decodeModRM(b[off:], pos+off, rexR, rexX, rexB, rexW)
+			if err != nil {
+				return Instruction{}, err
+			}
+			off += n
+			return Instruction{Mnemonic: mnemonic, Len: off, Operands: []string{modrm}}, nil
+		}
+		return Instruction{Mnemonic: "?", Len: off}, nil
+	}
+
+	if mnemonic, ok := oneByteMnemonics[b[off]]; ok {
+		off++
+		modrm, n, err := decodeModRM(b[off:], pos+off, rexR, rexX, rexB, rexW)
+		if err != nil {
+			return Instruction{}, err
+		}
+		off += n
+		return Instruction{Mnemonic: mnemonic, Len: off, Operands: []string{modrm}}, nil
+	}
+
+	// Unrecognized single-byte opcode: report it as unknown but still advance
+	// by one byte so a caller scanning a whole buffer can make progress.
+	return Instruction{Mnemonic: "?", Len: off + 1}, nil
+}
+
+// decodeVEX decodes a 2-byte (0xC5) or 3-byte (0xC4) VEX-prefixed
+// instruction: the VEX prefix itself, an opcode byte, and a ModRM operand
+// pair (destination from ModRM.reg, source from ModRM.rm). It covers the
+// register-direct and RIP-relative forms emitted by amd64.encodeVEXRegReg
+// and amd64.encodeVEXStaticConstLoad.
+func decodeVEX(b []byte, pos int) (Instruction, error) {
+	off := 0
+	var rExt, bExt bool
+	var pp byte
+
+	if b[off] == 0xc5 {
+		if len(b) < off+3 {
+			return Instruction{}, fmt.Errorf("disasm: truncated 2-byte VEX at %#x", pos)
+		}
+		b1 := b[off+1]
+		rExt = b1&0x80 == 0
+		pp = b1 & 0b11
+		off += 2
+	} else {
+		if len(b) < off+4 {
+			return Instruction{}, fmt.Errorf("disasm: truncated 3-byte VEX at %#x", pos)
+		}
+		b1 := b[off+1]
+		rExt = b1&0x80 == 0
+		bExt = b1&0x20 == 0
+		b2 := b[off+2]
+		pp = b2 & 0b11
+		off += 3
+	}
+
+	if off >= len(b) {
+		return Instruction{}, fmt.Errorf("disasm: truncated VEX opcode at %#x", pos)
+	}
+	opcode := b[off]
+	off++
+
+	mnemonic, ok := vexMnemonics[vexKey{pp: pp, opcode: opcode}]
+	if !ok {
+		return Instruction{Mnemonic: "?", Len: off}, nil
+	}
+	regOperand, rmOperand, n, err := decodeModRMXMM(b[off:], pos+off, rExt, bExt)
+	if err != nil {
+		return Instruction{}, err
+	}
+	off += n
+	return Instruction{Mnemonic: mnemonic, Len: off, Operands: []string{regOperand, rmOperand}}, nil
+}
+
+// decodeModRMXMM is decodeModRM's VEX counterpart: operands name XMM
+// registers rather than general-purpose ones, and unlike decodeModRM it
+// also decodes the ModRM.reg field, since VEX's three-operand forms need
+// both the destination (reg) and source (rm) operands.
+func decodeModRMXMM(b []byte, ripPos int, rExt, bExt bool) (regOperand, rmOperand string, n int, err error) {
+	if len(b) == 0 {
+		return "", "", 0, fmt.Errorf("disasm: truncated ModRM at %#x", ripPos)
+	}
+	modrm := b[0]
+	mod := modrm >> 6
+	regIdx := int((modrm >> 3) & 0b111)
+	if rExt {
+		regIdx += 8
+	}
+	regOperand = xmmNames[regIdx]
+	rm := modrm & 0b111
+	off := 1
+
+	if mod == 0b11 {
+		idx := int(rm)
+		if bExt {
+			idx += 8
+		}
+		return regOperand, xmmNames[idx], off, nil
+	}
+	if mod == 0b00 && rm == 0b101 {
+		if len(b) < off+4 {
+			return "", "", 0, fmt.Errorf("disasm: truncated RIP-relative disp32 at %#x", ripPos)
+		}
+		disp := int32(b[off]) | int32(b[off+1])<<8 | int32(b[off+2])<<16 | int32(b[off+3])<<24
+		off += 4
+		return regOperand, formatRIPRelative(disp), off, nil
+	}
+	return "", "", 0, fmt.Errorf("disasm: unsupported VEX ModRM mod=%d rm=%d at %#x", mod, rm, ripPos)
+}
+
+// decodeModRM parses a ModRM byte (and SIB/displacement if present) and
+// returns the operand's normalized text form along with the number of bytes
+// consumed. ripPos is the absolute position of the byte following ModRM at
+// the time decoding begins, used to fix up RIP-relative displacement once the
+// full instruction length is known to the caller... callers needing exact
+// RIP-relative resolution should prefer ResolveRIPRelative below, since the
+// instruction length is not known until this returns.
+func decodeModRM(b []byte, ripPos int, rexR, rexX, rexB, wide bool) (string, int, error) {
+	_ = rexR
+	if len(b) == 0 {
+		return "", 0, fmt.Errorf("disasm: truncated ModRM at %#x", ripPos)
+	}
+	modrm := b[0]
+	mod := modrm >> 6
+	rm := modrm & 0b111
+	off := 1
+
+	if mod == 0b11 {
+		// Register-direct operand.
+		idx := int(rm)
+		if rexB {
+			idx += 8
+		}
+		if wide {
+			return reg64Names[idx], off, nil
+		}
+		return reg32Names[idx], off, nil
+	}
+
+	if mod == 0b00 && rm == 0b101 {
+		// RIP-relative: disp32 follows directly.
+		if len(b) < off+4 {
+			return "", 0, fmt.Errorf("disasm: truncated RIP-relative disp32 at %#x", ripPos)
+		}
+		disp := int32(b[off]) | int32(b[off+1])<<8 | int32(b[off+2])<<16 | int32(b[off+3])<<24
+		off += 4
+		return formatRIPRelative(disp), off, nil
+	}
+
+	if rm == 0b100 {
+		// SIB byte follows.
+		if len(b) < off+1 {
+			return "", 0, fmt.Errorf("disasm: truncated SIB at %#x", ripPos)
+		}
+		off++
+	}
+
+	disp := int32(0)
+	switch mod {
+	case 0b01:
+		if len(b) < off+1 {
+			return "", 0, fmt.Errorf("disasm: truncated disp8 at %#x", ripPos)
+		}
+		disp = int32(int8(b[off]))
+		off++
+	case 0b10:
+		if len(b) < off+4 {
+			return "", 0, fmt.Errorf("disasm: truncated disp32 at %#x", ripPos)
+		}
+		disp = int32(b[off]) | int32(b[off+1])<<8 | int32(b[off+2])<<16 | int32(b[off+3])<<24
+		off += 4
+	}
+
+	idx := int(rm)
+	if rexB {
+		idx += 8
+	}
+	return formatBaseDisp(reg64Names[idx], disp), off, nil
+}
+
+// formatBaseDisp normalizes a [reg+disp] operand, using "-" for negative
+// displacements and omitting the displacement entirely when it is zero.
+func formatBaseDisp(base string, disp int32) string {
+	switch {
+	case disp == 0:
+		return fmt.Sprintf("[%s]", base)
+	case disp > 0:
+		return fmt.Sprintf("[%s + %#x]", base, disp)
+	default:
+		return fmt.Sprintf("[%s - %#x]", base, -disp)
+	}
+}
+
+// formatRIPRelative normalizes a [rip+disp]/[rip-disp] operand. The absolute
+// target address depends on the *end* of the enclosing instruction, which
+// this function does not know; callers that need the resolved address should
+// add the instruction's total length to rip before adding disp.
+func formatRIPRelative(disp int32) string {
+	switch {
+	case disp >= 0:
+		return fmt.Sprintf("[rip + %#x]", disp)
+	default:
+		return fmt.Sprintf("[rip - %#x]", -disp)
+	}
+}
+
+// ResolveRIPRelative computes the absolute address targeted by a
+// RIP-relative operand, given the offset of the byte immediately following
+// the full instruction (i.e. pos+Len from Decode) and the raw disp32 that was
+// encoded.
+func ResolveRIPRelative(instructionEnd int, disp int32) int {
+	return instructionEnd + int(disp)
+}
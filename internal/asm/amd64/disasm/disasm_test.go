@@ -0,0 +1,49 @@
+package disasm
+
+import (
+	"testing"
+
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+func TestDecode_cmpR12dRIPRelative(t *testing.T) {
+	// cmp r12d, dword ptr [rip + 0x14]
+	b := []byte{0x44, 0x3b, 0x25, 0x14, 0x0, 0x0, 0x0}
+	inst, err := Decode(b, 0)
+	require.NoError(t, err)
+	require.Equal(t, len(b), inst.Len)
+	require.Equal(t, []string{"[rip + 0x14]"}, inst.Operands)
+}
+
+func TestDecode_movdqu(t *testing.T) {
+	// movdqu xmm14, xmmword ptr [rip + 0xa]
+	b := []byte{0xf3, 0x44, 0xf, 0x6f, 0x35, 0xa, 0x0, 0x0, 0x0}
+	// The 0xf3 mandatory prefix is not modeled by this decoder's subset;
+	// callers are expected to strip/identify mandatory prefixes themselves
+	// before calling Decode, mirroring how the encoder keeps them separate
+	// from the REX+opcode stream.
+	inst, err := Decode(b[1:], 1)
+	require.NoError(t, err)
+	require.Equal(t, "[rip + 0xa]", inst.Operands[0])
+	require.Equal(t, len(b)-1, inst.Len)
+}
+
+func TestResolveRIPRelative(t *testing.T) {
+	require.Equal(t, 0x1b, ResolveRIPRelative(0x7, 0x14))
+}
+
+func TestDecode_registerDirect(t *testing.T) {
+	// cmp r12, qword ptr [rip] uses mod=11 only for register-direct forms;
+	// exercise that path directly via a synthetic ModRM byte instead (SETcc
+	// on a register operand, mod=11, rm=r12 w/ REX.B).
+	b := []byte{0x41, 0x0f, 0x94, 0xc4} // sete r12b
+	inst, err := Decode(b, 0)
+	require.NoError(t, err)
+	require.Equal(t, "sete", inst.Mnemonic)
+	require.Equal(t, "r12b", inst.Operands[0])
+}
+
+func TestDecode_empty(t *testing.T) {
+	_, err := Decode(nil, 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,35 @@
+package disasm
+
+import (
+	"fmt"
+	"io"
+)
+
+// DumpFunction writes a best-effort disassembly of a compiled function's
+// machine code to w, prefixed with its wasm function index. It is a
+// standalone utility for inspecting what the compiler backend generated for
+// a given function without reaching for an external disassembler; this tree
+// has no public wazero.RuntimeConfig to wire it up behind, so for now
+// callers invoke it directly. Instructions that do not decode (outside the
+// subset this package understands) are reported as "?" and skipped by the
+// prefix and opcode bytes Decode read for them, so the dump still makes
+// progress.
+func DumpFunction(w io.Writer, funcIndex uint32, code []byte) error {
+	if _, err := fmt.Fprintf(w, "function[%d]:\n", funcIndex); err != nil {
+		return err
+	}
+	for pos := 0; pos < len(code); {
+		inst, err := Decode(code[pos:], pos)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "  %#06x\t%s\n", pos, inst); err != nil {
+			return err
+		}
+		if inst.Len <= 0 {
+			inst.Len = 1
+		}
+		pos += inst.Len
+	}
+	return nil
+}
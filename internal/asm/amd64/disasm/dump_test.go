@@ -0,0 +1,33 @@
+package disasm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+func TestDumpFunction(t *testing.T) {
+	// sete al ; sub rsp, qword ptr [rip + 0x4] ; ud2 (unrecognized, just to
+	// exercise the "?" fallback path).
+	code := []byte{
+		0x0f, 0x94, 0xc0,
+		0x48, 0x2b, 0x25, 0x4, 0x0, 0x0, 0x0,
+		0x0f, 0x0b,
+	}
+	var out strings.Builder
+	err := DumpFunction(&out, 7, code)
+	require.NoError(t, err)
+
+	got := out.String()
+	require.True(t, strings.HasPrefix(got, "function[7]:\n"))
+	require.True(t, strings.Contains(got, "sete"))
+	require.True(t, strings.Contains(got, "[rip + 0x4]"))
+}
+
+func TestDumpFunction_emptyCode(t *testing.T) {
+	var out strings.Builder
+	err := DumpFunction(&out, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, "function[0]:\n", out.String())
+}
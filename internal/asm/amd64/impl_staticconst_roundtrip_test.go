@@ -0,0 +1,51 @@
+package amd64
+
+import (
+	"testing"
+
+	"github.com/streamdal/wazero/internal/asm"
+	"github.com/streamdal/wazero/internal/asm/amd64/disasm"
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+// TestAssemblerImpl_staticConst_roundTrip supplements
+// impl_staticconst_test.go's hand-decoded byte-array expectations with
+// decode(encode(inst)) == inst style assertions via the disasm package, for
+// the subset of register/opcode combinations disasm understands. The
+// existing hardcoded-array tests stay as-is: they pin exact bytes (useful
+// for catching any encoding regression at all, including in bytes disasm
+// doesn't model, like the mandatory prefix) and this file's coverage is
+// additive, not a replacement.
+func TestAssemblerImpl_staticConst_roundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		ins         asm.Instruction
+		reg         asm.Register
+		wantMnem    string
+		wantOperand string
+	}{
+		{name: "cmp r12d, [rip+x]", ins: CMPL, reg: RegR12, wantMnem: "cmp"},
+		{name: "cmp eax, [rip+x]", ins: CMPL, reg: RegAX, wantMnem: "cmp"},
+		{name: "cmp r12, [rip+x] (64-bit)", ins: CMPQ, reg: RegR12, wantMnem: "cmp"},
+		{name: "lea r11, [rip+x]", ins: LEAQ, reg: RegR11, wantMnem: "lea"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewAssembler()
+			c := asm.NewStaticConst([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+			err := a.CompileStaticConstToRegister(tc.ins, c, tc.reg)
+			require.NoError(t, err)
+
+			actual, err := a.Assemble()
+			require.NoError(t, err)
+
+			inst, err := disasm.Decode(actual, 0)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantMnem, inst.Mnemonic)
+			require.Equal(t, "[rip + 0x0]", inst.Operands[0])
+			// The constant must immediately follow the decoded instruction.
+			require.Equal(t, c.Raw, actual[inst.Len:inst.Len+len(c.Raw)])
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package amd64
+
+import "github.com/streamdal/wazero/internal/asm"
+
+// rmwFusible lists the arithmetic/compare instructions eligible for the
+// load-fuse and store-fuse rewrites in foldRMW. Each has a direct memory
+// operand form in the ISA (`ADD rReg, [mem]` / `ADD [mem], imm32`), so fusing
+// a preceding load into it saves one instruction and one register.
+var rmwFusible = map[asm.Instruction]bool{
+	ADDL: true, ADDQ: true,
+	SUBL: true, SUBQ: true,
+	ANDL: true, ANDQ: true,
+	ORL: true, ORQ: true,
+	XORL: true, XORQ: true,
+	CMPL: true, CMPQ: true,
+}
+
+// foldRMW is a peephole pass intended to run once over the node stream
+// immediately before encoding, fusing a dead temporary load into the
+// arithmetic/compare instruction that immediately consumes it:
+//
+//	MOV [mem], tmp ; OP tmp, rReg   =>  OP rReg, [mem]      (tmp dead after OP)
+//	MOV imm, tmp   ; OP tmp, [mem]  =>  OP [mem], imm32      (tmp dead, imm fits 32 bits)
+//
+// As with foldAddressingModes, a rewrite is skipped whenever the temporary is
+// referenced again before the next label/jump, since liveness cannot be
+// proven past such a boundary with a single linear scan.
+//
+// Assemble does not run this pass; see rmw_fold_test.go for coverage of the
+// rewrite itself, exercised directly against a hand-built node stream.
+func (a *AssemblerImpl) foldRMW() {
+	for n := a.root; n != nil; n = n.next {
+		if n.next == nil || !isMOV(n.instruction) || n.types.dst != operandTypeRegister {
+			continue
+		}
+		op := n.next
+		if !rmwFusible[op.instruction] {
+			continue
+		}
+		tmp := n.dstReg
+		switch {
+		case n.types.src == operandTypeMemory && op.srcReg == tmp && op.types.dst == operandTypeRegister:
+			// MOV [mem], tmp ; OP tmp, rReg => OP rReg, [mem]
+			if tempReferencedAfter(op.next, tmp) {
+				continue
+			}
+			op.srcReg = n.srcReg
+			op.srcConst = n.srcConst
+			op.types.src = operandTypeMemory
+			a.removeNode(n)
+		case n.types.src == operandTypeConst && op.types.dst == operandTypeMemory && op.srcReg == tmp && fitsInt32(n.srcConst):
+			// MOV imm, tmp ; OP tmp, [mem] => OP [mem], imm32
+			if tempReferencedAfter(op.next, tmp) {
+				continue
+			}
+			op.srcConst = n.srcConst
+			op.types.src = operandTypeConst
+			a.removeNode(n)
+		}
+	}
+}
+
+func isMOV(ins asm.Instruction) bool {
+	return ins == MOVL || ins == MOVQ
+}
+
+func fitsInt32(v int64) bool {
+	return v >= -(1<<31) && v < (1<<31)
+}
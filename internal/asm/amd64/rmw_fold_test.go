@@ -0,0 +1,91 @@
+package amd64
+
+import (
+	"testing"
+
+	"github.com/streamdal/wazero/internal/asm"
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+func TestFitsInt32(t *testing.T) {
+	require.True(t, fitsInt32(0))
+	require.True(t, fitsInt32(1<<31-1))
+	require.False(t, fitsInt32(1<<31))
+	require.False(t, fitsInt32(-(1<<31)-1))
+}
+
+func TestRmwFusible(t *testing.T) {
+	tests := []struct {
+		ins  asm.Instruction
+		want bool
+	}{
+		{ADDL, true}, {ADDQ, true}, {CMPQ, true}, {MOVL, false},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.want, rmwFusible[tc.ins])
+	}
+}
+
+func TestFoldRMW_loadThenOp(t *testing.T) {
+	a := NewAssembler()
+	// MOVL [RegBX], RegCX ; ADDL RegCX, RegDX  =>  ADDL [RegBX], RegDX
+	load := a.newNode(MOVL, operandTypeMemory, operandTypeRegister)
+	load.srcReg, load.dstReg = RegBX, RegCX
+	op := a.newNode(ADDL, operandTypeRegister, operandTypeRegister)
+	op.srcReg, op.dstReg = RegCX, RegDX
+
+	a.foldRMW()
+
+	got := nodes(a)
+	require.Equal(t, 1, len(got))
+	require.Equal(t, op, got[0])
+	require.Equal(t, operandTypeMemory, op.types.src)
+	require.Equal(t, RegBX, op.srcReg)
+}
+
+func TestFoldRMW_loadThenOp_tempStillLiveIsNotFolded(t *testing.T) {
+	a := NewAssembler()
+	load := a.newNode(MOVL, operandTypeMemory, operandTypeRegister)
+	load.srcReg, load.dstReg = RegBX, RegCX
+	op := a.newNode(ADDL, operandTypeRegister, operandTypeRegister)
+	op.srcReg, op.dstReg = RegCX, RegDX
+	reuse := a.newNode(MOVL, operandTypeRegister, operandTypeRegister)
+	reuse.srcReg, reuse.dstReg = RegCX, RegAX
+
+	a.foldRMW()
+
+	got := nodes(a)
+	require.Equal(t, 3, len(got))
+	require.Equal(t, load, got[0])
+}
+
+func TestFoldRMW_immThenOpToMemory(t *testing.T) {
+	a := NewAssembler()
+	// MOVL $7, RegCX ; CMPQ RegCX, [RegBX]  =>  CMPQ $7, [RegBX]
+	mov := a.newNode(MOVL, operandTypeConst, operandTypeRegister)
+	mov.srcConst, mov.dstReg = 7, RegCX
+	op := a.newNode(CMPQ, operandTypeRegister, operandTypeMemory)
+	op.srcReg, op.dstReg = RegCX, RegBX
+
+	a.foldRMW()
+
+	got := nodes(a)
+	require.Equal(t, 1, len(got))
+	require.Equal(t, op, got[0])
+	require.Equal(t, operandTypeConst, op.types.src)
+	require.Equal(t, int64(7), op.srcConst)
+}
+
+func TestFoldRMW_immDoesNotFitInt32IsNotFolded(t *testing.T) {
+	a := NewAssembler()
+	mov := a.newNode(MOVL, operandTypeConst, operandTypeRegister)
+	mov.srcConst, mov.dstReg = 1<<32, RegCX
+	op := a.newNode(CMPQ, operandTypeRegister, operandTypeMemory)
+	op.srcReg, op.dstReg = RegCX, RegBX
+
+	a.foldRMW()
+
+	got := nodes(a)
+	require.Equal(t, 2, len(got))
+	require.Equal(t, mov, got[0])
+}
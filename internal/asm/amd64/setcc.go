@@ -0,0 +1,121 @@
+package amd64
+
+import (
+	"fmt"
+
+	"github.com/streamdal/wazero/internal/asm"
+)
+
+// SETcc instructions. Each sets an r/m8 destination to 1 if the named
+// condition holds and 0 otherwise, letting the compiler materialize a
+// boolean result (i32.eqz, integer/float relops, select) without a
+// cmp+jmp+mov+jmp+mov branch sequence.
+const (
+	SETE asm.Instruction = iota + setccInstructionBase
+	SETNE
+	SETB
+	SETBE
+	SETA
+	SETAE
+	SETL
+	SETLE
+	SETG
+	SETGE
+	SETP
+	SETNP
+)
+
+// setccInstructionBase offsets the SETcc family above the existing
+// instruction constants so adding it cannot collide with other assignments.
+const setccInstructionBase asm.Instruction = 1 << 17
+
+// setccOpcode is the second opcode byte of the two-byte `0F 9x /0` encoding;
+// the first byte is always 0x0F.
+var setccOpcode = map[asm.Instruction]byte{
+	SETE:  0x94,
+	SETNE: 0x95,
+	SETB:  0x92,
+	SETBE: 0x96,
+	SETA:  0x97,
+	SETAE: 0x93,
+	SETL:  0x9c,
+	SETLE: 0x9e,
+	SETG:  0x9f,
+	SETGE: 0x9d,
+	SETP:  0x9a,
+	SETNP: 0x9b,
+}
+
+// conditionalRegisterStateToSETcc maps the conditional-register-state
+// vocabulary already used for conditional jumps onto the matching SETcc
+// instruction, so callers that pick a condition once can drive either a
+// conditional jump or a conditional set.
+var conditionalRegisterStateToSETcc = map[asm.ConditionalRegisterState]asm.Instruction{
+	asm.ConditionalRegisterStateE:  SETE,
+	asm.ConditionalRegisterStateNE: SETNE,
+	asm.ConditionalRegisterStateB:  SETB,
+	asm.ConditionalRegisterStateBE: SETBE,
+	asm.ConditionalRegisterStateA:  SETA,
+	asm.ConditionalRegisterStateAE: SETAE,
+	asm.ConditionalRegisterStateL:  SETL,
+	asm.ConditionalRegisterStateLE: SETLE,
+	asm.ConditionalRegisterStateG:  SETG,
+	asm.ConditionalRegisterStateGE: SETGE,
+}
+
+// CompileConditionalSet emits a SETcc instruction writing the boolean result
+// of cond into the low byte of reg, zero-extending the rest of reg is left to
+// the caller (typically via a prior XOR or a subsequent MOVZX).
+//
+// Unlike most other Compile* methods in this package, this emits directly to
+// the assembler's buffer rather than deferring to a node the backend encode
+// dispatch later visits: this tree has no base encode dispatch for the
+// SETcc family to hook into, and a deferred node that nothing ever visits
+// is worse than no node at all (it silently drops the instruction).
+func (a *AssemblerImpl) CompileConditionalSet(cond asm.ConditionalRegisterState, reg asm.Register) error {
+	setcc, ok := conditionalRegisterStateToSETcc[cond]
+	if !ok {
+		return fmt.Errorf("unsupported conditional state for SETcc: %d", cond)
+	}
+	var buf []byte
+	if err := encodeSETcc(&buf, setcc, reg); err != nil {
+		return err
+	}
+	a.buf.Write(buf)
+	return nil
+}
+
+// encodeSETcc emits the `0F 9x /0` form of a SETcc instruction, setting REX.B
+// (and forcing REX present even with no other bit set, since SETcc addressing
+// SPL/BPL/SIL/DIL requires it) when reg is R8-R15.
+func encodeSETcc(buf *[]byte, ins asm.Instruction, reg asm.Register) error {
+	opcode, ok := setccOpcode[ins]
+	if !ok {
+		return fmt.Errorf("%d is not a SETcc instruction", ins)
+	}
+	const rexPrefixBase byte = 0b0100_0000
+	const rexBitB byte = 0b0000_0001
+	rex := rexPrefixBase
+	modrmRM := byte(reg-RegAX) & 0b111
+	if isExtendedGPReg(reg) {
+		rex |= rexBitB
+	}
+	// Without a REX prefix, rm values 4-7 name AH/CH/DH/BH instead.
+	needsREX := reg == RegSP || reg == RegBP || reg == RegSI || reg == RegDI
+	if rex != rexPrefixBase || needsREX {
+		*buf = append(*buf, rex)
+	}
+	*buf = append(*buf, 0x0f, opcode, 0b11_000_000|modrmRM)
+	return nil
+}
+
+// isExtendedGPReg reports whether reg is one of the general-purpose
+// registers R8-R15, which need the REX.B extension bit set.
+func isExtendedGPReg(reg asm.Register) bool {
+	switch reg {
+	case RegR8, RegR9, RegR10, RegR11, RegR12, RegR13, RegR14, RegR15:
+		return true
+	default:
+		return false
+	}
+}
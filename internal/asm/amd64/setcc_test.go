@@ -0,0 +1,58 @@
+package amd64
+
+import (
+	"testing"
+
+	"github.com/streamdal/wazero/internal/asm"
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+func TestEncodeSETcc(t *testing.T) {
+	tests := []struct {
+		name string
+		ins  asm.Instruction
+		reg  asm.Register
+		exp  []byte
+	}{
+		{name: "sete al", ins: SETE, reg: RegAX, exp: []byte{0x0f, 0x94, 0xc0}},
+		{name: "setne r12b", ins: SETNE, reg: RegR12, exp: []byte{0x41, 0x0f, 0x95, 0xc4}},
+		{name: "setl r8b", ins: SETL, reg: RegR8, exp: []byte{0x41, 0x0f, 0x9c, 0xc0}},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var buf []byte
+			err := encodeSETcc(&buf, tc.ins, tc.reg)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, buf)
+		})
+	}
+}
+
+func TestCompileConditionalSet_unsupportedCondition(t *testing.T) {
+	a := NewAssembler()
+	err := a.CompileConditionalSet(asm.ConditionalRegisterState(0xff), RegAX)
+	require.Error(t, err)
+}
+
+func TestCompileConditionalSet_emitsEncodedBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		cond asm.ConditionalRegisterState
+		reg  asm.Register
+		exp  []byte
+	}{
+		{name: "sete al", cond: asm.ConditionalRegisterStateE, reg: RegAX, exp: []byte{0x0f, 0x94, 0xc0}},
+		{name: "setne r12b", cond: asm.ConditionalRegisterStateNE, reg: RegR12, exp: []byte{0x41, 0x0f, 0x95, 0xc4}},
+		{name: "setl r8b", cond: asm.ConditionalRegisterStateL, reg: RegR8, exp: []byte{0x41, 0x0f, 0x9c, 0xc0}},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewAssembler()
+			err := a.CompileConditionalSet(tc.cond, tc.reg)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, a.buf.Bytes())
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package amd64
+
+import (
+	"fmt"
+
+	"github.com/streamdal/wazero/internal/asm"
+	"github.com/streamdal/wazero/internal/platform"
+)
+
+// VEX-prefixed instructions. These mirror the legacy SSE instructions already
+// defined in this package (e.g. MOVDQU, SUBSS, UCOMISD) but are encoded with a
+// VEX prefix instead of a mandatory-prefix + REX + 0F escape, which allows the
+// non-destructive three-operand form: `VADD dst, src1, src2` instead of the
+// two-operand, destination-clobbering `ADD dst, src`.
+//
+// The numeric space is offset well above the legacy instruction constants so
+// that adding this family cannot collide with existing assignments.
+const vexInstructionBase asm.Instruction = 1 << 16
+
+const (
+	VMOVDQU asm.Instruction = vexInstructionBase + iota
+	VMOVUPD
+	VSUBSS
+	VSUBSD
+	VUCOMISD
+	VUCOMISS
+	VPADDB
+	VPADDW
+	VPADDD
+	VPADDQ
+	VPAND
+	VPANDN
+	VPOR
+	VPXOR
+)
+
+// vexOpInfo carries the fields needed to compute the m-mmmm, pp, and opcode
+// byte of a VEX-encoded instruction. It is the VEX analogue of the mandatory
+// prefix + escape byte(s) baked into the legacy encoder.
+type vexOpInfo struct {
+	// mmmmm selects the implied leading escape bytes, e.g. 0b00001 for a bare
+	// 0F escape, 0b00010 for 0F 38, 0b00011 for 0F 3A.
+	mmmmm byte
+	// pp selects the implied mandatory prefix: 0 (none), 1 (0x66), 2 (0xF3), 3 (0xF2).
+	pp byte
+	// opcode is the single opcode byte that follows the escape bytes.
+	opcode byte
+	// wide requests VEX.W=1, used by the few instructions that need it to select
+	// the 64-bit behavior of an otherwise identical opcode.
+	wide bool
+}
+
+var vexOpInfos = map[asm.Instruction]vexOpInfo{
+	VMOVDQU:  {mmmmm: 0b00001, pp: 2, opcode: 0x6f},
+	VMOVUPD:  {mmmmm: 0b00001, pp: 1, opcode: 0x10},
+	VSUBSS:   {mmmmm: 0b00001, pp: 2, opcode: 0x5c},
+	VSUBSD:   {mmmmm: 0b00001, pp: 3, opcode: 0x5c},
+	VUCOMISD: {mmmmm: 0b00001, pp: 1, opcode: 0x2e},
+	VUCOMISS: {mmmmm: 0b00001, pp: 0, opcode: 0x2e},
+	VPADDB:   {mmmmm: 0b00001, pp: 1, opcode: 0xfc},
+	VPADDW:   {mmmmm: 0b00001, pp: 1, opcode: 0xfd},
+	VPADDD:   {mmmmm: 0b00001, pp: 1, opcode: 0xfe},
+	VPADDQ:   {mmmmm: 0b00001, pp: 1, opcode: 0xd4},
+	VPAND:    {mmmmm: 0b00001, pp: 1, opcode: 0xdb},
+	VPANDN:   {mmmmm: 0b00001, pp: 1, opcode: 0xdf},
+	VPOR:     {mmmmm: 0b00001, pp: 1, opcode: 0xeb},
+	VPXOR:    {mmmmm: 0b00001, pp: 1, opcode: 0xef},
+}
+
+// hasAVX reports whether the host CPU supports AVX, gating selection of the
+// VEX-encoded path. Callers must fall back to the legacy SSE encodings when
+// this returns false.
+func hasAVX() bool {
+	return platform.CpuFeatures.Has(platform.CpuFeatureAmd64AVX)
+}
+
+// xmmIndex maps the XMM register constants to their 0-15 encoding index.
+// It is a plain switch, rather than arithmetic on the register constant's
+// underlying value, so this file does not depend on how RegX0..RegX15 are
+// numbered relative to each other or to the general-purpose registers.
+func xmmIndex(r asm.Register) (idx byte, ok bool) {
+	switch r {
+	case RegX0:
+		return 0, true
+	case RegX1:
+		return 1, true
+	case RegX2:
+		return 2, true
+	case RegX3:
+		return 3, true
+	case RegX4:
+		return 4, true
+	case RegX5:
+		return 5, true
+	case RegX6:
+		return 6, true
+	case RegX7:
+		return 7, true
+	case RegX8:
+		return 8, true
+	case RegX9:
+		return 9, true
+	case RegX10:
+		return 10, true
+	case RegX11:
+		return 11, true
+	case RegX12:
+		return 12, true
+	case RegX13:
+		return 13, true
+	case RegX14:
+		return 14, true
+	case RegX15:
+		return 15, true
+	default:
+		return 0, false
+	}
+}
+
+// encodeVEXRegReg appends a complete VEX-prefixed, register-to-register
+// instruction (VEX prefix, opcode, and a mod=11 ModRM byte) to *buf: the
+// non-destructive three-operand form `ins dst, src1, src2`, where src1 is
+// carried in VEX.vvvv and dst/src2 are the ModRM.reg/rm fields. This is the
+// one production encoder this file guarantees produces a complete,
+// decodable instruction; see vex_test.go for round-trips through the
+// disasm package.
+func encodeVEXRegReg(buf *[]byte, ins asm.Instruction, dst, src1, src2 asm.Register, l256 bool) error {
+	info, ok := vexOpInfos[ins]
+	if !ok {
+		return fmt.Errorf("%d is not a VEX instruction", ins)
+	}
+	dstIdx, ok := xmmIndex(dst)
+	if !ok {
+		return fmt.Errorf("VEX dst operand must be an XMM register, got %d", dst)
+	}
+	src2Idx, ok := xmmIndex(src2)
+	if !ok {
+		return fmt.Errorf("VEX src2 operand must be an XMM register, got %d", src2)
+	}
+
+	var vvvv byte = 0b1111 // "unused" per the VEX encoding, i.e. no non-destructive source.
+	if src1 != asm.NilRegister {
+		idx, ok := xmmIndex(src1)
+		if !ok {
+			return fmt.Errorf("VEX src1 operand must be an XMM register, got %d", src1)
+		}
+		vvvv = ^idx & 0xf // VEX.vvvv holds the register index inverted.
+	}
+
+	rBit := dstIdx >= 8  // ModRM.reg extension, stored inverted in the VEX prefix.
+	bBit := src2Idx >= 8 // ModRM.rm extension, stored inverted in the VEX prefix.
+	lBit := byte(0)
+	if l256 {
+		lBit = 1
+	}
+
+	if !bBit && info.mmmmm == 0b00001 && !info.wide {
+		b0 := byte(0xc5)
+		b1 := (negateBit(rBit) << 7) | ((vvvv & 0xf) << 3) | (lBit << 2) | info.pp
+		*buf = append(*buf, b0, b1, info.opcode)
+	} else {
+		b0 := byte(0xc4)
+		b1 := (negateBit(rBit) << 7) | (negateBit(false) << 6) | (negateBit(bBit) << 5) | info.mmmmm
+		w := byte(0)
+		if info.wide {
+			w = 1
+		}
+		b2 := (w << 7) | ((vvvv & 0xf) << 3) | (lBit << 2) | info.pp
+		*buf = append(*buf, b0, b1, b2, info.opcode)
+	}
+
+	modrm := byte(0b11_000_000) | ((dstIdx & 0b111) << 3) | (src2Idx & 0b111)
+	*buf = append(*buf, modrm)
+	return nil
+}
+
+// encodeVEXStaticConstLoad appends a complete VEX-prefixed instruction that
+// loads from [rip+0] into dst, i.e. a RIP-relative load whose target
+// immediately follows the instruction in the byte stream (disp32 = 0). This
+// matches the zero-displacement case the legacy encoder already supports
+// (see "cmp r12, qword ptr [rip]" in impl_staticconst_test.go) and lets
+// CompileStaticConstToRegisterVEX place the constant inline rather than
+// through the per-function/module constant pool.
+func encodeVEXStaticConstLoad(buf *[]byte, ins asm.Instruction, dst asm.Register) error {
+	info, ok := vexOpInfos[ins]
+	if !ok {
+		return fmt.Errorf("%d is not a VEX instruction", ins)
+	}
+	dstIdx, ok := xmmIndex(dst)
+	if !ok {
+		return fmt.Errorf("VEX dst operand must be an XMM register, got %d", dst)
+	}
+
+	rBit := dstIdx >= 8
+	if info.mmmmm == 0b00001 && !info.wide {
+		b0 := byte(0xc5)
+		b1 := (negateBit(rBit) << 7) | (0b1111 << 3) | info.pp
+		*buf = append(*buf, b0, b1, info.opcode)
+	} else {
+		b0 := byte(0xc4)
+		b1 := (negateBit(rBit) << 7) | (negateBit(false) << 6) | (negateBit(false) << 5) | info.mmmmm
+		w := byte(0)
+		if info.wide {
+			w = 1
+		}
+		b2 := (w << 7) | (0b1111 << 3) | info.pp
+		*buf = append(*buf, b0, b1, b2, info.opcode)
+	}
+
+	// mod=00, rm=101 (RIP-relative), reg=dst, disp32=0 follows.
+	modrm := byte(0b00_000_101) | ((dstIdx & 0b111) << 3)
+	*buf = append(*buf, modrm, 0, 0, 0, 0)
+	return nil
+}
+
+func negateBit(b bool) byte {
+	if b {
+		return 0
+	}
+	return 1
+}
+
+// isExtendedReg reports whether r is one of R8-R15 or X8-X15/XMM8-XMM15,
+// i.e. whether encoding it requires setting the corresponding REX/VEX
+// extension bit.
+func isExtendedReg(r asm.Register) bool {
+	if idx, ok := xmmIndex(r); ok {
+		return idx >= 8
+	}
+	switch r {
+	case RegR8, RegR9, RegR10, RegR11, RegR12, RegR13, RegR14, RegR15:
+		return true
+	default:
+		return false
+	}
+}
+
+// CompileRegisterToRegisterVEX emits the non-destructive three-operand form
+// `ins dst, src1, src2` using a VEX-prefixed instruction, falling back to
+// the legacy two-operand SSE instruction (clobbering dst, which must then
+// equal src1) when the host does not support AVX.
+func (a *AssemblerImpl) CompileRegisterToRegisterVEX(vexIns asm.Instruction, dst, src1, src2 asm.Register) error {
+	if !hasAVX() {
+		legacy, ok := vexToLegacy[vexIns]
+		if !ok {
+			return fmt.Errorf("no legacy fallback registered for VEX instruction %d", vexIns)
+		}
+		if dst != src1 {
+			return fmt.Errorf("legacy SSE fallback for %d requires dst == src1 (got dst=%d, src1=%d)", vexIns, dst, src1)
+		}
+		return a.CompileRegisterToRegister(legacy, src2, dst)
+	}
+	var buf []byte
+	if err := encodeVEXRegReg(&buf, vexIns, dst, src1, src2, false); err != nil {
+		return err
+	}
+	a.buf.Write(buf)
+	return nil
+}
+
+// CompileStaticConstToRegisterVEX is the VEX-encoded counterpart of
+// CompileStaticConstToRegister: it loads c into dst using a VEX-prefixed
+// instruction (e.g. VMOVDQU) instead of the legacy mandatory-prefix + 0F
+// form, falling back to the legacy encoding when the host does not support
+// AVX. Unlike CompileStaticConstToRegister, the constant is placed inline
+// immediately after the instruction rather than deferred to the
+// function/module constant pool, trading pooling/deduplication for a
+// simpler, directly-encoded instruction.
+func (a *AssemblerImpl) CompileStaticConstToRegisterVEX(vexIns asm.Instruction, c *asm.StaticConst, dstReg asm.Register) error {
+	if !hasAVX() {
+		legacy, ok := vexToLegacy[vexIns]
+		if !ok {
+			return fmt.Errorf("no legacy fallback registered for VEX instruction %d", vexIns)
+		}
+		return a.CompileStaticConstToRegister(legacy, c, dstReg)
+	}
+	if len(c.Raw)%2 != 0 {
+		return fmt.Errorf("const must be aligned to 2 bytes but was %d", len(c.Raw))
+	}
+	var buf []byte
+	if err := encodeVEXStaticConstLoad(&buf, vexIns, dstReg); err != nil {
+		return err
+	}
+	buf = append(buf, c.Raw...)
+	a.buf.Write(buf)
+	return nil
+}
+
+// vexToLegacy maps each VEX instruction to the legacy SSE instruction used as
+// a fallback when the host does not support AVX.
+var vexToLegacy = map[asm.Instruction]asm.Instruction{
+	VMOVDQU:  MOVDQU,
+	VMOVUPD:  MOVUPD,
+	VSUBSS:   SUBSS,
+	VSUBSD:   SUBSD,
+	VUCOMISD: UCOMISD,
+	VUCOMISS: UCOMISS,
+}
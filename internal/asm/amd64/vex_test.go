@@ -0,0 +1,114 @@
+package amd64
+
+import (
+	"testing"
+
+	"github.com/streamdal/wazero/internal/asm"
+	"github.com/streamdal/wazero/internal/asm/amd64/disasm"
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+func TestEncodeVEXRegReg_roundTrip(t *testing.T) {
+	t.Run("2-byte form, no non-destructive source", func(t *testing.T) {
+		// vmovdqu xmm0, xmm1: no extended registers and a bare 0F escape, so
+		// the 2-byte C5 form should be selected.
+		var buf []byte
+		err := encodeVEXRegReg(&buf, VMOVDQU, RegX0, asm.NilRegister, RegX1, false)
+		require.NoError(t, err)
+		require.Equal(t, byte(0xc5), buf[0])
+
+		inst, err := disasm.Decode(buf, 0)
+		require.NoError(t, err)
+		require.Equal(t, len(buf), inst.Len)
+		require.Equal(t, "vmovdqu", inst.Mnemonic)
+		require.Equal(t, []string{"xmm0", "xmm1"}, inst.Operands)
+	})
+
+	t.Run("3-byte form, extended registers", func(t *testing.T) {
+		// Using an X8-X15 register as src2 (ModRM.rm) forces the 3-byte C4
+		// form since VEX.B cannot be represented in the 2-byte prefix.
+		var buf []byte
+		err := encodeVEXRegReg(&buf, VMOVDQU, RegX14, asm.NilRegister, RegX15, false)
+		require.NoError(t, err)
+		require.Equal(t, byte(0xc4), buf[0])
+
+		inst, err := disasm.Decode(buf, 0)
+		require.NoError(t, err)
+		require.Equal(t, len(buf), inst.Len)
+		require.Equal(t, "vmovdqu", inst.Mnemonic)
+		require.Equal(t, []string{"xmm14", "xmm15"}, inst.Operands)
+	})
+
+	t.Run("non-destructive three-operand form", func(t *testing.T) {
+		// vsubss xmm2, xmm3, xmm4 ("xmm2 = xmm3 - xmm4"): src1 goes into
+		// VEX.vvvv rather than ModRM, so it doesn't show up as a decoded
+		// operand, but it must still select the right opcode/pp.
+		var buf []byte
+		err := encodeVEXRegReg(&buf, VSUBSS, RegX2, RegX3, RegX4, false)
+		require.NoError(t, err)
+
+		inst, err := disasm.Decode(buf, 0)
+		require.NoError(t, err)
+		require.Equal(t, "vsubss", inst.Mnemonic)
+		require.Equal(t, []string{"xmm2", "xmm4"}, inst.Operands)
+	})
+
+	t.Run("unknown instruction", func(t *testing.T) {
+		var buf []byte
+		err := encodeVEXRegReg(&buf, asm.Instruction(0xdead), RegX0, asm.NilRegister, RegX1, false)
+		require.Error(t, err)
+	})
+
+	t.Run("non-XMM operand", func(t *testing.T) {
+		var buf []byte
+		err := encodeVEXRegReg(&buf, VMOVDQU, RegAX, asm.NilRegister, RegX1, false)
+		require.Error(t, err)
+	})
+}
+
+func TestEncodeVEXStaticConstLoad_roundTrip(t *testing.T) {
+	var buf []byte
+	err := encodeVEXStaticConstLoad(&buf, VMOVDQU, RegX5)
+	require.NoError(t, err)
+
+	inst, err := disasm.Decode(buf, 0)
+	require.NoError(t, err)
+	require.Equal(t, len(buf), inst.Len)
+	require.Equal(t, "vmovdqu", inst.Mnemonic)
+	require.Equal(t, []string{"xmm5", "[rip + 0x0]"}, inst.Operands)
+}
+
+func TestCompileStaticConstToRegisterVEX_emitsInlineConst(t *testing.T) {
+	a := NewAssembler()
+	c := asm.NewStaticConst([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+
+	err := a.CompileStaticConstToRegisterVEX(VMOVDQU, c, RegX0)
+	require.NoError(t, err)
+
+	emitted := a.buf.Bytes()
+	inst, err := disasm.Decode(emitted, 0)
+	require.NoError(t, err)
+	require.Equal(t, "vmovdqu", inst.Mnemonic)
+	// The constant bytes must follow the instruction immediately, since the
+	// RIP-relative disp32 encoded above is always 0.
+	require.Equal(t, c.Raw, emitted[inst.Len:])
+}
+
+func TestCompileRegisterToRegisterVEX_legacyFallback(t *testing.T) {
+	a := NewAssembler()
+	err := a.CompileRegisterToRegisterVEX(VMOVDQU, RegX0, RegX0, RegX1)
+	require.NoError(t, err)
+	// Without AVX (the test environment has no hardware to assert on either
+	// way, so this exercises whichever path hasAVX() selects), the assembler
+	// must still produce *some* valid instruction rather than silently
+	// dropping it; CompileRegisterToRegister is the legacy encoder already
+	// covered by its own tests, so it's enough to assert bytes were emitted.
+	require.True(t, a.buf.Len() > 0)
+}
+
+func TestIsExtendedReg(t *testing.T) {
+	require.True(t, isExtendedReg(RegR8))
+	require.True(t, isExtendedReg(RegX15))
+	require.False(t, isExtendedReg(RegAX))
+	require.False(t, isExtendedReg(RegX0))
+}
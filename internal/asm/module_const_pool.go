@@ -0,0 +1,123 @@
+package asm
+
+import "crypto/sha256"
+
+// ConstHash content-addresses a StaticConst by the SHA-256 of its raw bytes,
+// so that a ModuleConstantPool can recognize two functions requesting the
+// same 16-byte SIMD mask (or any other constant) as the same blob instead of
+// emitting it once per function.
+type ConstHash [sha256.Size]byte
+
+// Hash returns the content hash used to deduplicate this constant across
+// functions in a ModuleConstantPool.
+func (c *StaticConst) Hash() ConstHash {
+	return sha256.Sum256(c.Raw)
+}
+
+// FinalizeOffset records offsetInBinary as this constant's final location
+// and invokes every callback registered via AddOffsetFinalizedCallback.
+// ModuleConstantPool.Layout calls this on every StaticConst instance that
+// shared a hash, passing that hash's single offset.
+func (c *StaticConst) FinalizeOffset(offsetInBinary uint64) {
+	for _, cb := range c.OffsetFinalizedCallbacks {
+		cb(offsetInBinary)
+	}
+}
+
+// ModuleConstantPool collects the StaticConst values referenced by every
+// function in a module and lays them out once, deduplicated by content hash,
+// at the end of the emitted code segment. This replaces the old per-function
+// pool for the common case where many functions reuse the same constant
+// (SIMD masks, float literals, vtable-like tables): previously each function
+// flushed its own copy into its own tail; now only the first occurrence of a
+// given hash is stored, and every later reference is resolved to that single
+// offset once all functions have been laid out.
+//
+// Functions whose earliest use of a constant would otherwise exceed the
+// architecture's RIP-relative displacement range still fall back to a
+// per-function copy placed within range: callers should keep using the
+// existing per-function pool for such constants and only route the rest
+// through AddConst, preserving the 2 GiB displacement invariant.
+type ModuleConstantPool struct {
+	// MaxDisplacement bounds how far a RIP-relative reference may reach into
+	// the shared pool; callers should fall back to a per-function copy for
+	// any constant whose first use is already this close to the end of the
+	// code segment.
+	MaxDisplacement int64
+
+	entries []*moduleConstEntry
+	byHash  map[ConstHash]*moduleConstEntry
+}
+
+type moduleConstEntry struct {
+	raw    []byte
+	refs   []*StaticConst
+	offset uint64
+}
+
+// NewModuleConstantPool returns an empty pool ready to accept constants via
+// AddConst.
+func NewModuleConstantPool() *ModuleConstantPool {
+	return &ModuleConstantPool{
+		MaxDisplacement: 1 << 31,
+		byHash:          map[ConstHash]*moduleConstEntry{},
+	}
+}
+
+// AddConst registers c with the pool, deduplicating against any
+// previously-added constant with identical content. useOffsetInBinary is the
+// offset, within the eventual code segment, of the instruction that will
+// reference c; it is accepted so call sites mirror the existing
+// per-function pool's AddConst signature, letting a function choose between
+// the shared and per-function pool without changing its call shape.
+func (p *ModuleConstantPool) AddConst(c *StaticConst, useOffsetInBinary uint64) {
+	_ = useOffsetInBinary
+	h := c.Hash()
+	if e, ok := p.byHash[h]; ok {
+		e.refs = append(e.refs, c)
+		return
+	}
+	e := &moduleConstEntry{raw: c.Raw, refs: []*StaticConst{c}}
+	p.byHash[h] = e
+	p.entries = append(p.entries, e)
+}
+
+// Layout appends every deduplicated constant to the end of codeSegment and
+// finalizes the offset of every StaticConst that referenced it. It must be
+// called exactly once, after every function in the module has been emitted,
+// since only then is the length of codeSegment (and hence each entry's final
+// offset) known.
+func (p *ModuleConstantPool) Layout(codeSegment []byte) []byte {
+	base := uint64(len(codeSegment))
+	for _, e := range p.entries {
+		e.offset = base
+		base += uint64(len(e.raw))
+		codeSegment = append(codeSegment, e.raw...)
+		for _, ref := range e.refs {
+			ref.FinalizeOffset(e.offset)
+		}
+	}
+	return codeSegment
+}
+
+// Size returns the total number of bytes the pool will add to the code
+// segment once laid out, i.e. the sum of each distinct constant's length.
+func (p *ModuleConstantPool) Size() uint64 {
+	var total uint64
+	for _, e := range p.entries {
+		total += uint64(len(e.raw))
+	}
+	return total
+}
+
+// Dedup returns the number of distinct constants in the pool and the total
+// number of constants added so far via AddConst; total-distinct is how many
+// turned out to be duplicates of an already-seen hash, which is useful for
+// tests and diagnostics verifying the pool actually deduplicates.
+func (p *ModuleConstantPool) Dedup() (distinct int, total int) {
+	for _, e := range p.entries {
+		distinct++
+		total += len(e.refs)
+	}
+	return
+}
@@ -0,0 +1,50 @@
+package asm
+
+import (
+	"testing"
+
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+// TestModuleConstantPool_twoFunctionsSharingAMask_resolveToOneOffset mirrors
+// this package's actual use case: two different functions in the same
+// module each compile a RIP-relative load of the byte-identical 16-byte
+// SIMD mask. The pool must store that mask exactly once, and both
+// functions' loads must finalize to that single shared offset - not two
+// separate copies, one per function, as the old per-function pool would
+// produce.
+func TestModuleConstantPool_twoFunctionsSharingAMask_resolveToOneOffset(t *testing.T) {
+	p := NewModuleConstantPool()
+
+	mask := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	fn1Load := NewStaticConst(append([]byte{}, mask...))
+	fn2Load := NewStaticConst(append([]byte{}, mask...))
+	fn1OtherLoad := NewStaticConst([]byte{0, 0, 0, 0})
+
+	// fn1 references the mask at its own instruction's offset within the
+	// eventual code segment (unused by Layout itself today, but mirrors how
+	// a real per-function caller would pass it), then a second,
+	// non-matching constant; fn2 references the same mask again.
+	p.AddConst(fn1Load, 0)
+	p.AddConst(fn1OtherLoad, 4)
+	p.AddConst(fn2Load, 100)
+
+	distinct, total := p.Dedup()
+	require.Equal(t, 2, distinct)
+	require.Equal(t, 3, total)
+
+	var fn1Offset, fn2Offset uint64
+	fn1Load.AddOffsetFinalizedCallback(func(o uint64) { fn1Offset = o })
+	fn2Load.AddOffsetFinalizedCallback(func(o uint64) { fn2Offset = o })
+
+	// codeSegment already holds both functions' machine code (8 bytes'
+	// worth, standing in for their actual instructions) before Layout runs,
+	// exactly as it would once every function in the module has been
+	// emitted.
+	codeSegment := p.Layout([]byte{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff, 0x11, 0x22})
+
+	require.Equal(t, fn1Offset, fn2Offset)
+	require.Equal(t, uint64(8), fn1Offset)
+	require.Equal(t, mask, codeSegment[fn1Offset:fn1Offset+uint64(len(mask))])
+	require.Equal(t, 8+len(mask)+len(fn1OtherLoad.Raw), len(codeSegment))
+}
@@ -0,0 +1,108 @@
+package compiler
+
+import "github.com/streamdal/wazero/internal/asm"
+
+// engine is this package's compiler-engine state. This tree's snapshot does
+// not include the rest of wazero's compiler-engine backend (instance
+// management, call caches, trampolines, and so on) - only the
+// constant-pool layout strategy WithSharedConstantPool configures - so
+// engine here tracks just that.
+type engine struct {
+	sharedConstantPool bool
+}
+
+// newEngine returns an engine configured by opts, starting from every
+// option's package-level default.
+func newEngine(opts ...EngineOption) *engine {
+	e := &engine{sharedConstantPool: sharedConstantPoolDefault}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// compiledFunction is what this package expects a function's compilation to
+// have already produced: its machine code, and the StaticConst values its
+// code references via a RIP-relative load. code must not itself contain a
+// flushed copy of those constants - CompileModule is responsible for
+// appending them, either once per function (the default) or once per module
+// (with the shared pool enabled) - only the load instructions' disp32
+// fields, to be finalized via each StaticConst's
+// AddOffsetFinalizedCallback once CompileModule places the constant.
+type compiledFunction struct {
+	code   []byte
+	consts []*asm.StaticConst
+}
+
+// CompileModule lays out the final code segment for every function in
+// functions, in order, and resolves every RIP-relative static-const
+// reference those functions made.
+//
+// With e.sharedConstantPool disabled (the default), each function's
+// constants are flushed into that same function's own tail immediately
+// after its code, mirroring the old per-function pool: two functions
+// referencing byte-identical constants each get their own copy.
+//
+// With it enabled, every function's constants are routed through a single
+// asm.ModuleConstantPool, deduplicated by content hash, and appended once at
+// the very end of the code segment - after every function has been placed,
+// since only then is each entry's final offset known. Two functions
+// referencing the same constant both have their AddOffsetFinalizedCallback
+// invoked with that one shared offset.
+//
+// asm.ModuleConstantPool's own doc explicitly leaves the 2 GiB
+// RIP-relative displacement invariant to its caller: a function whose
+// constant would end up farther than pool.MaxDisplacement from the pool
+// must fall back to a per-function copy instead of being routed through
+// AddConst. CompileModule is that caller, so it estimates each function's
+// worst-case distance to the pool (using the pool's size as if every
+// function's constants were shared, which can only overestimate the real,
+// deduplicated distance) and flushes any function that would exceed
+// MaxDisplacement locally, exactly as the disabled path does for that one
+// function, instead of routing it through the shared pool.
+func (e *engine) CompileModule(functions []compiledFunction) []byte {
+	if !e.sharedConstantPool {
+		var codeSegment []byte
+		for _, fn := range functions {
+			codeSegment = append(codeSegment, fn.code...)
+			for _, c := range fn.consts {
+				offset := uint64(len(codeSegment))
+				codeSegment = append(codeSegment, c.Raw...)
+				c.FinalizeOffset(offset)
+			}
+		}
+		return codeSegment
+	}
+
+	var codeOnly []byte
+	funcStart := make([]uint64, len(functions))
+	for i, fn := range functions {
+		funcStart[i] = uint64(len(codeOnly))
+		codeOnly = append(codeOnly, fn.code...)
+	}
+
+	candidatePool := asm.NewModuleConstantPool()
+	for _, fn := range functions {
+		for _, c := range fn.consts {
+			candidatePool.AddConst(c, 0)
+		}
+	}
+	worstCasePoolEnd := uint64(len(codeOnly)) + candidatePool.Size()
+
+	pool := asm.NewModuleConstantPool()
+	var codeSegment []byte
+	for i, fn := range functions {
+		codeSegment = append(codeSegment, fn.code...)
+		inRange := worstCasePoolEnd-funcStart[i] <= uint64(pool.MaxDisplacement)
+		for _, c := range fn.consts {
+			if inRange {
+				pool.AddConst(c, funcStart[i])
+				continue
+			}
+			offset := uint64(len(codeSegment))
+			codeSegment = append(codeSegment, c.Raw...)
+			c.FinalizeOffset(offset)
+		}
+	}
+	return pool.Layout(codeSegment)
+}
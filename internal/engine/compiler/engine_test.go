@@ -0,0 +1,57 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/streamdal/wazero/internal/asm"
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+func TestEngine_CompileModule_sharedPool_dedupesAcrossFunctions(t *testing.T) {
+	e := newEngine(WithSharedConstantPool(true))
+
+	mask := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	fn1Const := asm.NewStaticConst(append([]byte{}, mask...))
+	fn2Const := asm.NewStaticConst(append([]byte{}, mask...))
+
+	var offset1, offset2 uint64
+	fn1Const.AddOffsetFinalizedCallback(func(o uint64) { offset1 = o })
+	fn2Const.AddOffsetFinalizedCallback(func(o uint64) { offset2 = o })
+
+	fn1 := compiledFunction{code: []byte{0x01, 0x02, 0x03}, consts: []*asm.StaticConst{fn1Const}}
+	fn2 := compiledFunction{code: []byte{0x04, 0x05}, consts: []*asm.StaticConst{fn2Const}}
+
+	codeSegment := e.CompileModule([]compiledFunction{fn1, fn2})
+
+	// Both functions' RIP-relative references to the identical 16-byte mask
+	// must resolve to the same offset: the mask is appended to the code
+	// segment exactly once, after every function has been laid out, rather
+	// than once per function.
+	require.Equal(t, offset1, offset2)
+	require.Equal(t, uint64(len(fn1.code)+len(fn2.code)), offset1)
+	require.Equal(t, mask, codeSegment[offset1:offset1+uint64(len(mask))])
+	require.Equal(t, len(fn1.code)+len(fn2.code)+len(mask), len(codeSegment))
+}
+
+func TestEngine_CompileModule_defaultPool_flushesPerFunction(t *testing.T) {
+	e := newEngine() // sharedConstantPool defaults to false.
+
+	mask := []byte{1, 2, 3, 4}
+	fn1Const := asm.NewStaticConst(append([]byte{}, mask...))
+	fn2Const := asm.NewStaticConst(append([]byte{}, mask...))
+
+	var offset1, offset2 uint64
+	fn1Const.AddOffsetFinalizedCallback(func(o uint64) { offset1 = o })
+	fn2Const.AddOffsetFinalizedCallback(func(o uint64) { offset2 = o })
+
+	fn1 := compiledFunction{code: []byte{0xaa}, consts: []*asm.StaticConst{fn1Const}}
+	fn2 := compiledFunction{code: []byte{0xbb, 0xcc}, consts: []*asm.StaticConst{fn2Const}}
+
+	codeSegment := e.CompileModule([]compiledFunction{fn1, fn2})
+
+	// Without the shared pool, each function flushes its own copy into its
+	// own tail immediately, so the two identical masks land at two distinct
+	// offsets instead of being deduplicated.
+	require.True(t, offset1 != offset2)
+	require.Equal(t, len(fn1.code)+len(mask)+len(fn2.code)+len(mask), len(codeSegment))
+}
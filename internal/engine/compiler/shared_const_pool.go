@@ -0,0 +1,27 @@
+package compiler
+
+// sharedConstantPoolDefault is the initial value of engine.sharedConstantPool,
+// which, when true, routes SIMD masks, float literals, and
+// other StaticConst values through a single asm.ModuleConstantPool laid out
+// once at the end of the module's codeSegment instead of flushing a copy
+// into every function's own tail. It defaults to false so existing callers
+// see no change in code layout unless they opt in.
+var sharedConstantPoolDefault = false
+
+// WithSharedConstantPool controls whether engine.CompileModule deduplicates
+// constants across functions in a module via a single module-wide constant
+// pool (enabled) or keeps flushing a separate copy per function (disabled,
+// the default); see CompileModule for exactly where that routing happens.
+// Enabling it is most valuable for modules with many functions that reuse
+// the same SIMD masks or float constants, at the cost of slightly more
+// bookkeeping during compilation to track which hashes have already been
+// seen.
+func WithSharedConstantPool(enabled bool) EngineOption {
+	return func(e *engine) {
+		e.sharedConstantPool = enabled
+	}
+}
+
+// EngineOption configures optional, non-default behavior of the compiler
+// engine at construction time.
+type EngineOption func(*engine)
@@ -0,0 +1,138 @@
+// Package threads implements the runtime-side half of the WebAssembly
+// threads proposal: a wait-queue keyed by (memory, address) that blocks the
+// calling goroutine until another goroutine notifies the same address, or
+// the caller's timeout elapses, plus a bounded worker pool for the
+// goroutines executing those threads. Nothing in this repository lowers
+// memory.atomic.wait32/64 or memory.atomic.notify into calls against
+// WaitQueue, so nothing here calls into this package. See waitqueue_test.go and workerpool_test.go, in particular
+// TestWaitQueue_and_WorkerPool_driveThreadsTogether, for the shape a real
+// caller (a memory.atomic.wait32/64 host call dispatching into a
+// WorkerPool-bounded goroutine) is expected to take.
+package threads
+
+import (
+	"sync"
+	"time"
+)
+
+// waiterKey identifies a unique (shared memory, byte address) pair being
+// waited on. memoryID distinguishes which `shared` memory instance owns the
+// address, since two modules (or two memories in one module) may otherwise
+// use the same address.
+type waiterKey struct {
+	memoryID uintptr
+	address  uint32
+}
+
+// WaitQueue blocks/wakes goroutines executing memory.atomic.wait32/64 and
+// memory.atomic.notify against shared memories. A single WaitQueue is meant
+// to be shared by every goroutine servicing threads spawned from the same
+// module instance.
+type WaitQueue struct {
+	mu      sync.Mutex
+	waiters map[waiterKey][]chan struct{}
+}
+
+// NewWaitQueue returns an empty WaitQueue.
+func NewWaitQueue() *WaitQueue {
+	return &WaitQueue{waiters: map[waiterKey][]chan struct{}{}}
+}
+
+// WaitResult mirrors the three outcomes memory.atomic.wait32/64 must be able
+// to report to the guest: 0 ("ok", woken by a notify), 1 ("not-equal", the
+// expected value didn't match and the caller never blocked), or 2 ("timed
+// out").
+type WaitResult uint32
+
+const (
+	WaitResultOK WaitResult = iota
+	WaitResultNotEqual
+	WaitResultTimedOut
+)
+
+// Wait blocks the calling goroutine on (memoryID, address) until Notify
+// wakes it or timeout elapses. A negative timeout blocks forever, matching
+// the wasm spec's encoding of "no timeout" as -1. checkStillEqual is called
+// exactly once, under the queue's lock, immediately before registering the
+// wait, so the caller can atomically verify the memory still holds the
+// expected value without racing a concurrent Notify.
+func (q *WaitQueue) Wait(memoryID uintptr, address uint32, timeout time.Duration, checkStillEqual func() bool) WaitResult {
+	key := waiterKey{memoryID, address}
+
+	q.mu.Lock()
+	if !checkStillEqual() {
+		q.mu.Unlock()
+		return WaitResultNotEqual
+	}
+	ch := make(chan struct{})
+	q.waiters[key] = append(q.waiters[key], ch)
+	q.mu.Unlock()
+
+	if timeout < 0 {
+		<-ch
+		return WaitResultOK
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-ch:
+		return WaitResultOK
+	case <-timer.C:
+		q.removeWaiter(key, ch)
+		return WaitResultTimedOut
+	}
+}
+
+// Notify wakes up to count waiters blocked on (memoryID, address), returning
+// how many were actually woken. count is taken literally: a count of 0 wakes
+// nobody.
+func (q *WaitQueue) Notify(memoryID uintptr, address uint32, count uint32) uint32 {
+	key := waiterKey{memoryID, address}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	chans := q.waiters[key]
+	if len(chans) == 0 || count == 0 {
+		return 0
+	}
+
+	n := count
+	if uint32(len(chans)) < n {
+		n = uint32(len(chans))
+	}
+	for i := uint32(0); i < n; i++ {
+		close(chans[i])
+	}
+	remaining := chans[n:]
+	if len(remaining) == 0 {
+		delete(q.waiters, key)
+	} else {
+		q.waiters[key] = remaining
+	}
+	return n
+}
+
+func (q *WaitQueue) removeWaiter(key waiterKey, ch chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	chans := q.waiters[key]
+	for i, c := range chans {
+		if c == ch {
+			// Check whether Notify already woke us between the timer firing
+			// and acquiring the lock; if so, draining here would hang, so
+			// only remove if still present and unclosed.
+			select {
+			case <-c:
+				return
+			default:
+			}
+			q.waiters[key] = append(chans[:i], chans[i+1:]...)
+			if len(q.waiters[key]) == 0 {
+				delete(q.waiters, key)
+			}
+			return
+		}
+	}
+}
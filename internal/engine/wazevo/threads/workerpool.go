@@ -0,0 +1,71 @@
+package threads
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPoolExhausted is returned by WorkerPool.TrySpawn when the pool is already
+// running its configured maximum number of workers.
+var ErrPoolExhausted = errors.New("threads: worker pool exhausted")
+
+// WorkerPool caps the number of goroutines servicing threads spawned by a
+// single module instance, mirroring how an emscripten pthread module
+// requests a fixed-size worker pool from its host rather than spawning
+// unboundedly. There is no public wazero.RuntimeConfig knob that constructs
+// one of these yet (a prior version of this comment claimed
+// WithMaxWorkerThreads; no such method exists in this tree) — for now a host
+// module that spawns threads constructs a WorkerPool directly and calls
+// Spawn/TrySpawn from its pthread_create-equivalent host function.
+type WorkerPool struct {
+	slots chan struct{}
+}
+
+// NewWorkerPool returns a WorkerPool allowing up to max concurrently-running
+// workers. max <= 0 means unlimited, matching the behavior before this knob
+// existed.
+func NewWorkerPool(max int) *WorkerPool {
+	if max <= 0 {
+		return &WorkerPool{}
+	}
+	return &WorkerPool{slots: make(chan struct{}, max)}
+}
+
+// Spawn runs fn in a new goroutine once a slot is available, blocking until
+// either a slot frees up or ctx is canceled. The slot is released
+// automatically when fn returns.
+func (p *WorkerPool) Spawn(ctx context.Context, fn func(context.Context)) error {
+	if p.slots == nil {
+		go fn(ctx)
+		return nil
+	}
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	go func() {
+		defer func() { <-p.slots }()
+		fn(ctx)
+	}()
+	return nil
+}
+
+// TrySpawn is the non-blocking variant of Spawn: it returns ErrPoolExhausted
+// immediately instead of waiting for a free slot.
+func (p *WorkerPool) TrySpawn(ctx context.Context, fn func(context.Context)) error {
+	if p.slots == nil {
+		go fn(ctx)
+		return nil
+	}
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		return ErrPoolExhausted
+	}
+	go func() {
+		defer func() { <-p.slots }()
+		fn(ctx)
+	}()
+	return nil
+}
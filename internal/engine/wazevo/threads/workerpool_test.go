@@ -0,0 +1,38 @@
+package threads
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+func TestWorkerPool_limitsConcurrency(t *testing.T) {
+	p := NewWorkerPool(1)
+	ctx := context.Background()
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	require.NoError(t, p.Spawn(ctx, func(context.Context) {
+		close(started)
+		<-block
+	}))
+	<-started
+
+	err := p.TrySpawn(ctx, func(context.Context) {})
+	require.True(t, err == ErrPoolExhausted)
+
+	close(block)
+}
+
+func TestWorkerPool_unlimitedWhenZero(t *testing.T) {
+	p := NewWorkerPool(0)
+	done := make(chan struct{})
+	require.NoError(t, p.Spawn(context.Background(), func(context.Context) { close(done) }))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker never ran")
+	}
+}
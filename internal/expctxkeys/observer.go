@@ -0,0 +1,6 @@
+package expctxkeys
+
+// ObserverKey is the context.Context key used by experimental.WithObserver
+// / experimental.GetObserver, following the same pattern as
+// CloseNotifierKey.
+type ObserverKey struct{}
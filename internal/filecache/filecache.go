@@ -0,0 +1,268 @@
+// Package filecache implements a persistent, on-disk compilation cache,
+// intended to back a future wazero.NewCompilationCacheWithDir (no such
+// public constructor exists in this tree yet). Entries are content-addressed
+// by the SHA-256 of the source wasm bytes plus a version tag, so the cache
+// can be safely shared by multiple processes (e.g. replicas of the same
+// service, or repeated CI runs) pointed at the same directory without a
+// separate coordination service.
+package filecache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Key identifies a single cache entry: the content hash of the wasm binary
+// plus a version tag covering anything that changes what compiling that
+// binary produces (wazero build id, enabled CoreFeatures, target arch/OS).
+// Two processes with different version tags never collide, even when
+// sharing a directory, since the tag is folded into the key itself.
+type Key struct {
+	WasmHash [sha256.Size]byte
+	Version  string
+}
+
+// String renders k as the filename-safe string used on disk.
+func (k Key) String() string {
+	return hex.EncodeToString(k.WasmHash[:]) + "-" + k.Version
+}
+
+// NewKey hashes wasm and pairs it with version to form a Key.
+func NewKey(wasm []byte, version string) Key {
+	return Key{WasmHash: sha256.Sum256(wasm), Version: version}
+}
+
+// Event is reported to a Cache's OnEvent callback, if set, for every Get/Put
+// and the eviction decisions Prune makes.
+type Event struct {
+	Kind EventKind
+	// Key is set for EventHit/EventMiss/EventPut. Prune evicts purely by
+	// file metadata without re-parsing the key out of the filename, so
+	// EventEvict leaves Key at its zero value and sets Path instead.
+	Key Key
+	// Path is set for EventEvict, naming the file that was removed.
+	Path string
+	// Bytes is the size of the entry involved, when known.
+	Bytes int64
+}
+
+// EventKind enumerates the kinds of Event a Cache can report.
+type EventKind int
+
+const (
+	EventHit EventKind = iota
+	EventMiss
+	EventPut
+	EventEvict
+)
+
+// Cache is a directory-backed compilation cache shared, potentially, by
+// multiple processes. Every exported method is safe for concurrent use by
+// multiple goroutines and multiple processes pointed at the same Dir.
+type Cache struct {
+	Dir string
+	// OnEvent, if non-nil, is invoked synchronously for every cache
+	// operation; implementations that need to do real work (metrics export,
+	// logging) should make it non-blocking themselves.
+	OnEvent func(Event)
+}
+
+// New returns a Cache rooted at dir, creating dir if it does not exist.
+func New(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("filecache: create cache dir: %w", err)
+	}
+	return &Cache{Dir: dir}, nil
+}
+
+func (c *Cache) path(k Key) string {
+	return filepath.Join(c.Dir, k.String())
+}
+
+func (c *Cache) lockPath(k Key) string {
+	return c.path(k) + ".lock"
+}
+
+func (c *Cache) emit(kind EventKind, k Key, n int64) {
+	if c.OnEvent != nil {
+		c.OnEvent(Event{Kind: kind, Key: k, Bytes: n})
+	}
+}
+
+// Get reads the cached compiled bytes for k, reporting EventHit/EventMiss.
+// A missing entry is not an error: ok is false and err is nil.
+func (c *Cache) Get(k Key) (data []byte, ok bool, err error) {
+	data, err = os.ReadFile(c.path(k))
+	if errors.Is(err, os.ErrNotExist) {
+		c.emit(EventMiss, k, 0)
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	// Refresh the modification time so Prune sees this entry as recently used.
+	now := time.Now()
+	_ = os.Chtimes(c.path(k), now, now)
+	c.emit(EventHit, k, int64(len(data)))
+	return data, true, nil
+}
+
+// Put stores data under k, taking an exclusive cross-process lock for the
+// duration of the write so that two processes racing to compile and cache
+// the same module never interleave writes to the same file. The write is
+// performed to a temporary file and renamed into place, so a reader can
+// never observe a partially-written entry.
+func (c *Cache) Put(k Key, data []byte) error {
+	unlock, err := c.lock(k)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tmp, err := os.CreateTemp(c.Dir, k.String()+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, c.path(k)); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	c.emit(EventPut, k, int64(len(data)))
+	return nil
+}
+
+// lock acquires a simple, portable cross-process advisory lock for k using
+// exclusive file creation: creating a file with O_EXCL is atomic on every
+// platform Go supports, which is enough to serialize writers without
+// depending on a platform-specific flock syscall. It polls briefly rather
+// than blocking forever so a crashed process holding a stale lock cannot
+// wedge every other process indefinitely.
+func (c *Cache) lock(k Key) (unlock func(), err error) {
+	path := c.lockPath(k)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("filecache: timed out waiting for lock on %s", k)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// entryInfo pairs a cache entry's path with its on-disk metadata, used by
+// Prune to decide what to evict.
+type entryInfo struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Prune evicts entries until the cache's total size is at most maxBytes and
+// every remaining entry was last used within maxAge, evicting the
+// least-recently-used entries first. A zero maxBytes or maxAge disables that
+// respective bound. It returns the number of bytes freed.
+func (c *Cache) Prune(maxBytes int64, maxAge time.Duration) (freed int64, err error) {
+	entries, total, err := c.list()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var kept []entryInfo
+	for _, e := range entries {
+		if maxAge > 0 && now.Sub(e.modTime) > maxAge {
+			if err := c.evict(e); err != nil {
+				return freed, err
+			}
+			freed += e.size
+			continue
+		}
+		kept = append(kept, e)
+	}
+	total -= freed
+
+	if maxBytes > 0 && total > maxBytes {
+		// Oldest-first: entries are already discovered in directory order,
+		// so do a simple selection sort by modTime rather than pulling in
+		// sort for what is, in practice, a small number of entries.
+		for i := 0; i < len(kept); i++ {
+			min := i
+			for j := i + 1; j < len(kept); j++ {
+				if kept[j].modTime.Before(kept[min].modTime) {
+					min = j
+				}
+			}
+			kept[i], kept[min] = kept[min], kept[i]
+		}
+		i := 0
+		for total > maxBytes && i < len(kept) {
+			e := kept[i]
+			if err := c.evict(e); err != nil {
+				return freed, err
+			}
+			freed += e.size
+			total -= e.size
+			i++
+		}
+	}
+
+	return freed, nil
+}
+
+func (c *Cache) list() ([]entryInfo, int64, error) {
+	ents, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return nil, 0, err
+	}
+	var out []entryInfo
+	var total int64
+	for _, e := range ents {
+		name := e.Name()
+		// Put's temp files are named "<key>.tmp-<random>" (see
+		// os.CreateTemp), so filepath.Ext never matches plain ".tmp": it
+		// returns everything after the last dot, i.e. ".tmp-<random>".
+		// Match the literal pattern Put actually produces instead.
+		if filepath.Ext(name) == ".lock" || strings.Contains(name, ".tmp-") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, entryInfo{path: filepath.Join(c.Dir, name), size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+	}
+	return out, total, nil
+}
+
+func (c *Cache) evict(e entryInfo) error {
+	if err := os.Remove(e.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if c.OnEvent != nil {
+		c.OnEvent(Event{Kind: EventEvict, Path: e.path, Bytes: e.size})
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package filecache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+func TestCache_PutGet(t *testing.T) {
+	c, err := New(t.TempDir())
+	require.NoError(t, err)
+
+	var events []Event
+	c.OnEvent = func(e Event) { events = append(events, e) }
+
+	k := NewKey([]byte("module bytes"), "v1")
+
+	_, ok, err := c.Get(k)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, c.Put(k, []byte("compiled bytes")))
+
+	data, ok, err := c.Get(k)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "compiled bytes", string(data))
+
+	require.Equal(t, []EventKind{EventMiss, EventPut, EventHit}, []EventKind{events[0].Kind, events[1].Kind, events[2].Kind})
+}
+
+func TestKey_differentVersionsDontCollide(t *testing.T) {
+	wasm := []byte("same bytes")
+	k1 := NewKey(wasm, "v1")
+	k2 := NewKey(wasm, "v2")
+	require.True(t, k1.String() != k2.String())
+}
+
+func TestCache_PruneByMaxBytes(t *testing.T) {
+	c, err := New(t.TempDir())
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		k := NewKey([]byte{byte(i)}, "v1")
+		require.NoError(t, c.Put(k, make([]byte, 10)))
+		time.Sleep(5 * time.Millisecond) // ensure distinct mod times for LRU ordering
+	}
+
+	freed, err := c.Prune(15, 0)
+	require.NoError(t, err)
+	require.True(t, freed >= 10)
+
+	entries, total, err := c.list()
+	require.NoError(t, err)
+	require.True(t, total <= 15)
+	require.True(t, len(entries) <= 2)
+}
+
+func TestCache_list_excludesStrayTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir)
+	require.NoError(t, err)
+
+	k := NewKey([]byte("module bytes"), "v1")
+	require.NoError(t, c.Put(k, []byte("compiled bytes")))
+
+	// Simulate a temp file left behind by a crashed Put: os.CreateTemp names
+	// these "<key>.tmp-<random>", which does not end in literal ".tmp".
+	strayName := k.String() + ".tmp-3851928470"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, strayName), make([]byte, 1000), 0o644))
+
+	entries, total, err := c.list()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	require.Equal(t, int64(len("compiled bytes")), total)
+}
+
+func TestCache_PruneByMaxAge(t *testing.T) {
+	c, err := New(t.TempDir())
+	require.NoError(t, err)
+
+	k := NewKey([]byte("x"), "v1")
+	require.NoError(t, c.Put(k, []byte("data")))
+
+	freed, err := c.Prune(0, time.Nanosecond)
+	require.NoError(t, err)
+	require.Equal(t, int64(len("data")), freed)
+
+	_, ok, err := c.Get(k)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
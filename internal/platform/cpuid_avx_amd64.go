@@ -0,0 +1,7 @@
+package platform
+
+// CpuFeatureAmd64AVX reports CPUID.(EAX=1):ECX.AVX[bit 28], i.e. support for
+// the VEX-encoded instruction forms. It is queried by the amd64 assembler to
+// decide whether to emit VEX-prefixed (VMOVDQU, VSUBSS, ...) or legacy SSE
+// instructions.
+const CpuFeatureAmd64AVX = CpuFeatureFlags(1 << 28)
@@ -0,0 +1,140 @@
+//go:build !(linux || darwin || windows) && !tinygo
+
+package sysfs
+
+import (
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/streamdal/wazero/experimental/sys"
+	"github.com/streamdal/wazero/internal/fsapi"
+)
+
+// poll implements `Poll` as documented on fsapi.File via a file descriptor,
+// using a portable adaptive-backoff loop instead of a platform poll/epoll
+// syscall. It exists for platforms wazero doesn't have a native poller for
+// (FreeBSD, OpenBSD, NetBSD, illumos, ...), where the alternative used to be
+// an immediate sys.ENOSYS, degrading WASI poll_oneoff badly for the common
+// case of a guest waiting on stdin or a pipe.
+//
+// The strategy: put the descriptor in non-blocking mode (best effort - if
+// the platform can't, return NotImplemented so guests get a clear error
+// instead of spinning forever), then alternate a non-blocking read attempt
+// with a sleep that backs off from 1µs up to a 1ms cap, bounded by the
+// caller's timeout. A byte read ahead of time in order to detect readiness
+// is stashed in a per-fd buffer and must be returned to the next real read
+// of that descriptor, via TakeReadahead, so poll never silently drops
+// guest-visible data.
+//
+// This tree's snapshot does not include the fsapi.File implementation this
+// platform would use (the file that would implement Read() by calling
+// syscall.Read directly isn't present here), so TakeReadahead has no real
+// caller yet: every byte poll probes ahead of time on a real build would
+// currently be lost to the guest's next read. See
+// TestPollThenRead_recoversReadaheadByte for the exact sequence a Read
+// implementation must follow - call TakeReadahead first and prepend its
+// byte, only falling back to syscall.Read for the remainder - and
+// ClearReadahead's doc comment for the matching obligation on Close.
+func poll(fd uintptr, flag fsapi.Pflag, timeoutMillisec int32) (bool, sys.Errno) {
+	if flag != fsapi.POLLIN {
+		// Only readability is meaningful for the stdin/pipe-wait use case
+		// this fallback targets; anything else degrades the same as before.
+		return false, sys.ENOSYS
+	}
+
+	if peekedReadahead.has(fd) {
+		return true, 0
+	}
+
+	if errno := setNonblock(fd, true); errno != 0 {
+		return false, sys.ENOSYS
+	}
+	defer setNonblock(fd, false)
+
+	deadline := time.Now().Add(time.Duration(timeoutMillisec) * time.Millisecond)
+	hasDeadline := timeoutMillisec >= 0
+
+	backoff := time.Microsecond
+	const maxBackoff = time.Millisecond
+
+	buf := make([]byte, 1)
+	for {
+		n, err := syscall.Read(int(fd), buf)
+		if n > 0 {
+			peekedReadahead.put(fd, buf[0])
+			return true, 0
+		}
+		if n == 0 && err == nil {
+			// EOF: the descriptor is "ready" in the sense that the next
+			// read returns immediately, matching what a real poller would
+			// report for a closed pipe.
+			return true, 0
+		}
+		if err != nil && err != syscall.EAGAIN && err != syscall.EWOULDBLOCK && err != syscall.EINTR {
+			return false, sys.UnwrapOSError(err)
+		}
+
+		if hasDeadline && !time.Now().Before(deadline) {
+			return false, 0
+		}
+
+		time.Sleep(backoff)
+		if backoff < maxBackoff {
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		runtime.Gosched()
+	}
+}
+
+// readaheadBuffer stashes the single byte a poll call had to read
+// non-blockingly in order to detect readiness, so that byte is not lost to
+// the guest: the next real read of that descriptor drains it first via
+// TakeReadahead.
+type readaheadBuffer struct {
+	mu   sync.Mutex
+	data map[uintptr]byte
+}
+
+var peekedReadahead = &readaheadBuffer{data: map[uintptr]byte{}}
+
+func (r *readaheadBuffer) has(fd uintptr) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.data[fd]
+	return ok
+}
+
+func (r *readaheadBuffer) put(fd uintptr, b byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data[fd] = b
+}
+
+// TakeReadahead returns a byte previously stolen from fd by poll's
+// readiness probe, if any, so a subsequent real read can prepend it before
+// issuing its own syscall.
+func TakeReadahead(fd uintptr) (b byte, ok bool) {
+	peekedReadahead.mu.Lock()
+	defer peekedReadahead.mu.Unlock()
+	b, ok = peekedReadahead.data[fd]
+	if ok {
+		delete(peekedReadahead.data, fd)
+	}
+	return
+}
+
+// ClearReadahead discards any byte stashed for fd without returning it.
+// Every File.Close on this platform must call this for the fd it closes:
+// file descriptors are reused by the OS once closed, so a readahead byte
+// left behind under the old fd's number would otherwise be handed to
+// whatever unrelated file the next open() call gets that same number for.
+func ClearReadahead(fd uintptr) {
+	peekedReadahead.mu.Lock()
+	defer peekedReadahead.mu.Unlock()
+	delete(peekedReadahead.data, fd)
+}
@@ -0,0 +1,81 @@
+//go:build !(linux || darwin || windows) && !tinygo
+
+package sysfs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/streamdal/wazero/internal/fsapi"
+	"github.com/streamdal/wazero/internal/testing/require"
+)
+
+func TestReadaheadBuffer_putTake(t *testing.T) {
+	var fd uintptr = 42
+	require.False(t, peekedReadahead.has(fd))
+
+	peekedReadahead.put(fd, 'x')
+	require.True(t, peekedReadahead.has(fd))
+
+	b, ok := TakeReadahead(fd)
+	require.True(t, ok)
+	require.Equal(t, byte('x'), b)
+
+	require.False(t, peekedReadahead.has(fd))
+	_, ok = TakeReadahead(fd)
+	require.False(t, ok)
+}
+
+func TestClearReadahead_discardsWithoutReturning(t *testing.T) {
+	var fd uintptr = 43
+	peekedReadahead.put(fd, 'y')
+	require.True(t, peekedReadahead.has(fd))
+
+	ClearReadahead(fd)
+
+	require.False(t, peekedReadahead.has(fd))
+	_, ok := TakeReadahead(fd)
+	require.False(t, ok)
+}
+
+// readAll simulates the Read a real fsapi.File implementation on this
+// platform must perform: drain any readahead byte poll stashed before
+// falling back to a real syscall.Read for the rest.
+func readAll(fd uintptr, f *os.File, n int) []byte {
+	out := make([]byte, 0, n)
+	if b, ok := TakeReadahead(fd); ok {
+		out = append(out, b)
+		n--
+	}
+	if n > 0 {
+		rest := make([]byte, n)
+		read, _ := f.Read(rest)
+		out = append(out, rest[:read]...)
+	}
+	return out
+}
+
+// TestPollThenRead_recoversReadaheadByte proves poll's readiness probe never
+// drops the byte it peeked: poll on a real pipe, then drive a Read the way a
+// real fsapi.File.Read must, and confirm the full message survives.
+func TestPollThenRead_recoversReadaheadByte(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	fd := r.Fd()
+	ready, errno := poll(fd, fsapi.POLLIN, 1000)
+	require.True(t, ready)
+	require.True(t, errno == 0)
+	require.True(t, peekedReadahead.has(fd))
+
+	got := readAll(fd, r, len("hello"))
+	require.Equal(t, "hello", string(got))
+	require.False(t, peekedReadahead.has(fd))
+
+	ClearReadahead(fd)
+}
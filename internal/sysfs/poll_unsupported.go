@@ -1,4 +1,4 @@
-//go:build !(linux || darwin || windows) || tinygo
+//go:build tinygo
 
 package sysfs
 
@@ -8,6 +8,12 @@ import (
 )
 
 // poll implements `Poll` as documented on fsapi.File via a file descriptor.
+//
+// tinygo builds keep returning ENOSYS rather than using the generic fallback
+// in poll_fallback.go: that fallback leans on setNonblock, which tinygo's
+// runtime does not implement for arbitrary file descriptors (see
+// nonblock_unsupported.go), so there is nothing for it to poll non-blocking
+// against.
 func poll(uintptr, fsapi.Pflag, int32) (bool, sys.Errno) {
 	return false, sys.ENOSYS
 }